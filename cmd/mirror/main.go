@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -11,17 +12,17 @@ import (
 
 	"github.com/robfig/cron/v3"
 	"lemwood_mirror/internal/auth"
-	"lemwood_mirror/internal/browser"
 	"lemwood_mirror/internal/config"
 	"lemwood_mirror/internal/db"
 	"lemwood_mirror/internal/downloader"
 	gh "lemwood_mirror/internal/github"
 	"lemwood_mirror/internal/server"
+	"lemwood_mirror/internal/source"
 )
 
 type LauncherState struct {
 	Name     string
-	RepoURL  string
+	Source   string
 	Version  string
 	LastScan time.Time
 }
@@ -47,10 +48,34 @@ func main() {
 	if err := s.InitFromDisk(); err != nil {
 		log.Printf("初始化索引失败: %v", err)
 	}
-	ghc := gh.NewClient(cfg.GitHubToken)
+	var app *gh.AppCredentials
+	if cfg.GitHubApp != nil && cfg.GitHubApp.AppID != 0 {
+		keyPath := cfg.GitHubApp.PrivateKeyPath
+		if !filepath.IsAbs(keyPath) {
+			keyPath = filepath.Join(projectRoot, keyPath)
+		}
+		key, err := os.ReadFile(keyPath)
+		if err != nil {
+			log.Fatalf("读取 GitHub App 私钥失败: %v", err)
+		}
+		app = &gh.AppCredentials{AppID: cfg.GitHubApp.AppID, InstallationID: cfg.GitHubApp.InstallationID, PrivateKey: key}
+	}
+	ghc, err := gh.NewClient(cfg.GitHubTokenList(), app)
+	if err != nil {
+		log.Fatalf("创建 GitHub 客户端失败: %v", err)
+	}
+	s.GitHub = ghc
+
+	// 下载队列：扫描只登记新版本，资源由队列在后台下载，失败后按指数退避重试
+	downer := downloader.NewDownloader(cfg.DownloadTimeoutMinutes, cfg.ConcurrentDownloads, cfg.PerHostDownloads)
+	downer.SetRateLimit(int64(cfg.DownloadRateLimitKB) << 10)
+	windows, err := downloader.ParseWindows(cfg.DownloadWindows)
+	if err != nil {
+		log.Fatalf("解析下载窗口失败: %v", err)
+	}
+	downer.SetWindows(windows, int64(cfg.DownloadWindowMinMB)<<20)
 
 	var mu sync.Mutex
-	var scanMu sync.Mutex
 	launchers := make(map[string]*LauncherState)
 	for _, l := range cfg.Launchers {
 		ls := &LauncherState{Name: l.Name}
@@ -62,95 +87,307 @@ func main() {
 		launchers[l.Name] = ls
 	}
 
-	scan := func() {
-		if !scanMu.TryLock() {
-			log.Printf("扫描已在进行中，跳过此次执行")
+	// launcherState 返回启动器的状态，通过管理界面新增的启动器在首次扫描时创建
+	launcherState := func(name string) *LauncherState {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := launchers[name]; !ok {
+			launchers[name] = &LauncherState{Name: name, Version: s.GetLatestVersion(name)}
+		}
+		return launchers[name]
+	}
+
+	// 下载选项和发布回调均按当前配置生成，进程重启后从数据库恢复的版本同样适用
+	queue := downloader.NewQueue(downer, base, downloader.QueueHooks{
+		Options: func(name string) (downloader.Options, error) {
+			current := s.CurrentConfig()
+			if lcfg, ok := current.Launcher(name); ok {
+				return downloadOptions(current, lcfg)
+			}
+			return downloader.Options{}, fmt.Errorf("启动器 %s 已从配置中删除", name)
+		},
+		// 新的 latest 版本下载并校验通过、即将发布时才清除该启动器所有旧版本的 latest 标记
+		BeforePublish: func(name, version string, isLatest bool) error {
+			if isLatest {
+				if err := s.ClearLatestFlags(name); err != nil {
+					log.Printf("%s: 清除旧版本 latest 标记失败: %v", name, err)
+				}
+			}
+			return nil
+		},
+		Published: func(name, version, indexPath string, isLatest bool) {
+			s.UpdateIndex(name, version, indexPath)
+			if !isLatest {
+				// 历史版本、beta 版本，或下载期间已有更新版本的旧 latest 版本
+				return
+			}
+			ls := launcherState(name)
+			mu.Lock()
+			ls.Version = version
+			ls.LastScan = time.Now()
+			mu.Unlock()
+			log.Printf("%s: 已更新至 %s", name, version)
+		},
+	})
+	s.Jobs = queue
+	go queue.Run(context.Background())
+
+	// mirrorLauncher 检查并镜像单个启动器，由 scanner 保证同一启动器的扫描不会并发执行。
+	// 启动器配置在每次扫描时从当前配置中读取，管理界面修改的上游、过滤规则和令牌会在下一次扫描时生效
+	mirrorLauncher := func(name string) {
+		live := s.CurrentConfig()
+		lcfg, ok := live.Launcher(name)
+		if !ok {
+			log.Printf("%s: 启动器已从配置中删除，跳过扫描", name)
+			return
+		}
+		ls := launcherState(lcfg.Name)
+		timeout := time.Duration(live.DownloadTimeoutMinutes) * time.Minute
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		p, err := source.New(lcfg, live, ghc)
+		if err != nil {
+			log.Printf("%s: 创建上游失败: %v", lcfg.Name, err)
 			return
 		}
-		defer scanMu.Unlock()
+		log.Printf("%s: 使用上游 %s", lcfg.Name, p.Name())
+		// 记录解析后的上游地址，供 Webhook 和 GitHub API 兼容接口按仓库匹配启动器
+		s.RecordSource(lcfg.Name, p.Name())
+		mu.Lock()
+		ls.Source = p.Name()
+		mu.Unlock()
+		rel, err := p.LatestRelease(ctx)
+		if err != nil {
+			log.Printf("%s: 获取最新 release 失败: %v", lcfg.Name, err)
+			return
+		}
+		version := rel.Version()
+
+		// 检查是否已经是最新版本，避免重复下载
+		mu.Lock()
+		current := ls.Version
+		mu.Unlock()
+		if current == version {
+			log.Printf("%s: 版本 %s 已是最新，跳过下载", lcfg.Name, version)
+		} else if downloader.IsQuarantined(base, lcfg.Name, version) {
+			log.Printf("%s: 版本 %s 已被隔离，跳过下载", lcfg.Name, version)
+		} else {
+			if err := queue.Submit(lcfg.Name, rel, true); err != nil {
+				log.Printf("%s: 提交下载任务失败: %v", lcfg.Name, err)
+				return
+			}
+			log.Printf("%s: 发现新版本 %s，已加入下载队列", lcfg.Name, version)
+		}
+
+		if lcfg.KeepHistory > 0 {
+			syncHistory(ctx, p, s, queue, lcfg, base)
+		}
+		if lcfg.BetaChannel {
+			syncBeta(ctx, p, s, queue, lcfg, base)
+		}
+	}
+
+	// scanLauncher 扫描单个启动器；该启动器正在扫描时不会丢弃此次请求，而是在当前扫描结束后再扫描一次
+	scanner := server.NewScanner()
+	scanLauncher := func(name string) {
+		if scanner.Run(name, func() { mirrorLauncher(name) }) == server.ScanQueued {
+			log.Printf("%s: 扫描已在进行中，将在其结束后再扫描一次", name)
+		}
+	}
+
+	// scanGroup 并发扫描一组启动器
+	scanGroup := func(names []string) {
 		log.Printf("扫描开始")
 		wg := sync.WaitGroup{}
-		for _, lcfg := range cfg.Launchers {
-			lcfg := lcfg
+		for _, name := range names {
+			name := name
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				timeout := time.Duration(cfg.DownloadTimeoutMinutes) * time.Minute
-				ctx, cancel := context.WithTimeout(context.Background(), timeout)
-				defer cancel()
-				repoURL, err := browser.ResolveRepoURL(lcfg.SourceURL, lcfg.RepoSelector)
-				if err != nil {
-					log.Printf("%s: 解析仓库地址失败: %v", lcfg.Name, err)
-					return
-				}
-				log.Printf("%s: 使用仓库 %s", lcfg.Name, repoURL)
-				owner, repo, err := gh.ParseOwnerRepo(repoURL)
-				if err != nil {
-					log.Printf("%s: 解析 owner/repo 失败: %v", lcfg.Name, err)
-					return
-				}
-				rel, resp, err := ghc.LatestRelease(ctx, owner, repo)
-				if err != nil {
-					log.Printf("%s: 获取最新 release 失败: %v", lcfg.Name, err)
-					gh.BackoffIfRateLimited(resp)
-					return
-				}
-				version := rel.GetTagName()
-				if version == "" {
-					version = rel.GetName()
-				}
-				
-				// 检查是否已经是最新版本，避免重复下载
-				mu.Lock()
-				ls := launchers[lcfg.Name]
-				if ls.Version == version {
-					mu.Unlock()
-					log.Printf("%s: 版本 %s 已是最新，跳过下载", lcfg.Name, version)
-					return
-				}
-				mu.Unlock()
-				
-				// 清除该启动器所有旧版本的 latest 标记
-				if err := s.ClearLatestFlags(lcfg.Name); err != nil {
-					log.Printf("%s: 清除旧版本 latest 标记失败: %v", lcfg.Name, err)
-				}
-				
-				downer := downloader.NewDownloader(cfg.DownloadTimeoutMinutes, cfg.ConcurrentDownloads)
-				infoPath, err := downer.DownloadLatest(ctx, lcfg.Name, base, cfg.ProxyURL, cfg.AssetProxyURL, cfg.XgetEnabled, cfg.XgetDomain, rel, cfg.ServerAddress, cfg.ServerPort, cfg.DownloadUrlBase, true)
-				if err != nil {
-					log.Printf("%s: 下载失败: %v", lcfg.Name, err)
-					return
-				}
-				
-				s.UpdateIndex(lcfg.Name, version, infoPath)
-				mu.Lock()
-				ls.RepoURL = repoURL
-				ls.Version = version
-				ls.LastScan = time.Now()
-				mu.Unlock()
-				log.Printf("%s: 已更新至 %s", lcfg.Name, version)
+				scanLauncher(name)
 			}()
 		}
 		wg.Wait()
 		log.Printf("扫描完成")
 	}
 
+	// scan 扫描全部启动器，用于初始扫描和手动触发。启动器列表取自当前配置，包括通过管理界面新增的启动器
+	scan := func() {
+		var names []string
+		for _, lcfg := range s.CurrentConfig().Launchers {
+			names = append(names, lcfg.Name)
+		}
+		scanGroup(names)
+	}
+
+	// scanByName 按名称异步扫描单个启动器，启动器不存在时返回 server.ScanNotFound
+	scanByName := func(name string) server.ScanStatus {
+		if _, ok := s.CurrentConfig().Launcher(name); !ok {
+			return server.ScanNotFound
+		}
+		return scanner.Trigger(name, func() { mirrorLauncher(name) })
+	}
+
 	// 初始扫描
 	go scan()
 
-	// 定时任务
-	c := cron.New()
-	_, err = c.AddFunc(cfg.CheckCron, scan)
-	if err != nil {
-		log.Fatalf("无效的 cron 表达式 %q: %v", cfg.CheckCron, err)
+	// 定时任务：配置了 check_cron 的启动器使用独立的定时任务，其余启动器共用全局的 check_cron。
+	// 任务在执行时才从当前配置中确定要扫描的启动器；管理界面保存配置后按新配置重建定时任务
+	var cronMu sync.Mutex
+	var c *cron.Cron
+	schedule := func(cfg *config.Config) error {
+		nc := cron.New()
+		for _, lcfg := range cfg.Launchers {
+			if lcfg.CheckCron == "" {
+				continue
+			}
+			name := lcfg.Name
+			if _, err := nc.AddFunc(lcfg.CheckCron, func() { scanLauncher(name) }); err != nil {
+				return fmt.Errorf("%s: 无效的 cron 表达式 %q: %w", name, lcfg.CheckCron, err)
+			}
+			log.Printf("%s: 使用独立的检查计划 %s", name, lcfg.CheckCron)
+		}
+		_, err := nc.AddFunc(cfg.CheckCron, func() {
+			var names []string
+			for _, lcfg := range s.CurrentConfig().Launchers {
+				if lcfg.CheckCron == "" {
+					names = append(names, lcfg.Name)
+				}
+			}
+			if len(names) > 0 {
+				scanGroup(names)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("无效的 cron 表达式 %q: %w", cfg.CheckCron, err)
+		}
+		cronMu.Lock()
+		old := c
+		c = nc
+		cronMu.Unlock()
+		nc.Start()
+		if old != nil {
+			old.Stop()
+		}
+		return nil
+	}
+	if err := schedule(cfg); err != nil {
+		log.Fatal(err)
+	}
+	s.OnConfigUpdate = func(cfg *config.Config) {
+		if err := schedule(cfg); err != nil {
+			log.Printf("更新定时任务失败，沿用原有的检查计划: %v", err)
+		}
 	}
-	c.Start()
-	defer c.Stop()
 
 	// 带有手动扫描端点的 HTTP 服务器
 	addr := fmt.Sprintf(":%d", cfg.ServerPort)
 	log.Printf("正在启动服务器于 %s", addr)
-	if err := server.StartHTTPWithScan(addr, s, scan); err != nil {
+	if err := server.StartHTTPWithScan(addr, s, scan, scanByName); err != nil {
 		log.Fatalf("http 服务器出错: %v", err)
 	}
 }
+
+// downloadOptions 根据全局配置和启动器配置生成下载选项
+func downloadOptions(cfg *config.Config, lcfg config.LauncherConfig) (downloader.Options, error) {
+	filter, err := downloader.NewAssetFilter(lcfg.IncludeAssets, lcfg.ExcludeAssets)
+	if err != nil {
+		return downloader.Options{}, err
+	}
+	accels, err := accelerators(cfg)
+	if err != nil {
+		return downloader.Options{}, err
+	}
+	return downloader.Options{
+		ProxyURL:         cfg.ProxyURL,
+		Accelerators:     accels,
+		ServerAddress:    cfg.ServerAddress,
+		ServerPort:       cfg.ServerPort,
+		DownloadUrlBase:  cfg.DownloadUrlBase,
+		Filter:           filter,
+		SourceArchives:   lcfg.SourceArchives,
+		Segments:         lcfg.Segments,
+		SegmentThreshold: int64(lcfg.SegmentThresholdMB) << 20,
+		AssetHeader: func(downloadURL string) http.Header {
+			return source.AssetHeader(lcfg, downloadURL)
+		},
+	}, nil
+}
+
+// accelerators 返回下载 GitHub 资源时依次尝试的加速方式。未配置 accelerators 时，
+// 按旧配置依次使用 asset_proxy_url、Xget，最后回退到直连。
+func accelerators(cfg *config.Config) ([]downloader.Accelerator, error) {
+	var list []downloader.Accelerator
+	for _, a := range cfg.Accelerators {
+		switch a.Type {
+		case downloader.AccelDirect:
+		case downloader.AccelXget, downloader.AccelPrefix:
+			if a.URL == "" {
+				return nil, fmt.Errorf("加速方式 %s 缺少 url", a.Type)
+			}
+		default:
+			return nil, fmt.Errorf("未知的加速方式类型 %q", a.Type)
+		}
+		name := a.Name
+		if name == "" {
+			name = a.Type
+			if a.URL != "" {
+				name += ":" + a.URL
+			}
+		}
+		list = append(list, downloader.Accelerator{Name: name, Type: a.Type, URL: a.URL})
+	}
+	if len(list) > 0 {
+		return list, nil
+	}
+	if cfg.AssetProxyURL != "" {
+		list = append(list, downloader.Accelerator{Name: "prefix:" + cfg.AssetProxyURL, Type: downloader.AccelPrefix, URL: cfg.AssetProxyURL})
+	}
+	if cfg.XgetEnabled && cfg.XgetDomain != "" {
+		list = append(list, downloader.Accelerator{Name: "xget:" + cfg.XgetDomain, Type: downloader.AccelXget, URL: cfg.XgetDomain})
+	}
+	return append(list, downloader.Accelerator{Name: downloader.AccelDirect, Type: downloader.AccelDirect}), nil
+}
+
+// syncHistory 镜像最近 KeepHistory 个 release 中本地缺失的版本，这些版本不会被标记为 latest。
+func syncHistory(ctx context.Context, p source.Provider, s *server.State, queue *downloader.Queue, lcfg config.LauncherConfig, base string) {
+	releases, err := p.ListReleases(ctx, lcfg.KeepHistory, false)
+	if err != nil {
+		log.Printf("%s: 获取历史 release 失败: %v", lcfg.Name, err)
+		return
+	}
+	for _, rel := range releases {
+		version := rel.Version()
+		if s.HasVersion(lcfg.Name, version) || downloader.IsQuarantined(base, lcfg.Name, version) {
+			continue
+		}
+		log.Printf("%s: 补齐历史版本 %s", lcfg.Name, version)
+		if err := queue.Submit(lcfg.Name, rel, false); err != nil {
+			log.Printf("%s: 提交历史版本 %s 的下载任务失败: %v", lcfg.Name, version, err)
+		}
+	}
+}
+
+// syncBeta 镜像最新的预发布版本（beta 通道），预发布版本不会影响稳定版的 latest 标记。
+func syncBeta(ctx context.Context, p source.Provider, s *server.State, queue *downloader.Queue, lcfg config.LauncherConfig, base string) {
+	rel, err := source.LatestPrerelease(ctx, p)
+	if err != nil {
+		log.Printf("%s: 获取预发布版本失败: %v", lcfg.Name, err)
+		return
+	}
+	if rel == nil {
+		return
+	}
+	version := rel.Version()
+	if s.HasVersion(lcfg.Name, version) {
+		log.Printf("%s: beta 版本 %s 已是最新，跳过下载", lcfg.Name, version)
+		return
+	}
+	if downloader.IsQuarantined(base, lcfg.Name, version) {
+		log.Printf("%s: beta 版本 %s 已被隔离，跳过下载", lcfg.Name, version)
+		return
+	}
+	if err := queue.Submit(lcfg.Name, rel, false); err != nil {
+		log.Printf("%s: 提交 beta 版本 %s 的下载任务失败: %v", lcfg.Name, version, err)
+	}
+}
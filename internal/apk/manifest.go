@@ -0,0 +1,292 @@
+package apk
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Info 是从 APK 中提取的元数据
+type Info struct {
+	Package     string   `json:"package"`
+	VersionCode int64    `json:"version_code"`
+	VersionName string   `json:"version_name"`
+	MinSDK      int      `json:"min_sdk,omitempty"`
+	TargetSDK   int      `json:"target_sdk,omitempty"`
+	ABIs        []string `json:"abis,omitempty"`    // 包含的原生库架构，为空表示不含原生库（通用包）
+	Signers     []string `json:"signers,omitempty"` // 签名证书的 SHA-256 指纹，为空表示未签名或签名无法解析
+}
+
+// Android 框架属性的资源 ID，属性名可能被混淆，按资源 ID 匹配更可靠
+const (
+	attrVersionCode      = 0x0101021b
+	attrVersionName      = 0x0101021c
+	attrMinSdkVersion    = 0x0101020c
+	attrTargetSdkVersion = 0x01010270
+)
+
+// 二进制 XML 的块类型
+const (
+	chunkStringPool   = 0x0001
+	chunkXML          = 0x0003
+	chunkResourceMap  = 0x0180
+	chunkStartElement = 0x0102
+)
+
+// Res_value 的数据类型
+const (
+	typeString = 0x03
+	typeIntDec = 0x10
+	typeIntHex = 0x11
+)
+
+// Parse 读取 APK 文件，解析其中的二进制 AndroidManifest.xml 并统计原生库架构。
+// 签名证书与清单相互独立，由调用方通过 Signers 读取后填入 Info.Signers。
+func Parse(path string) (*Info, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开 APK 失败: %w", err)
+	}
+	defer zr.Close()
+	return parseZip(&zr.Reader)
+}
+
+func parseZip(zr *zip.Reader) (*Info, error) {
+	var manifest []byte
+	abis := make(map[string]bool)
+	for _, f := range zr.File {
+		if f.Name == "AndroidManifest.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			manifest, err = io.ReadAll(io.LimitReader(rc, 16<<20))
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// 原生库位于 lib/<abi>/xxx.so
+		if parts := strings.Split(f.Name, "/"); len(parts) == 3 && parts[0] == "lib" && strings.HasSuffix(parts[2], ".so") {
+			abis[parts[1]] = true
+		}
+	}
+	if manifest == nil {
+		return nil, errors.New("APK 中没有 AndroidManifest.xml")
+	}
+	info, err := parseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	for abi := range abis {
+		info.ABIs = append(info.ABIs, abi)
+	}
+	sort.Strings(info.ABIs)
+	return info, nil
+}
+
+// parseManifest 解析二进制 XML 格式的 AndroidManifest.xml，只读取 manifest 与 uses-sdk 元素
+func parseManifest(data []byte) (*Info, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXML {
+		return nil, errors.New("不是二进制 XML 格式的清单文件")
+	}
+	var strs []string
+	var resIDs []uint32
+	info := &Info{}
+
+	off := int(binary.LittleEndian.Uint16(data[2:]))
+	if off < 8 {
+		return nil, errors.New("清单文件头长度无效")
+	}
+	// 只解析根数据块声明的范围，文件比声明的短说明已被截断
+	total := int(binary.LittleEndian.Uint32(data[4:]))
+	if total < off || total > len(data) {
+		return nil, errors.New("清单文件被截断")
+	}
+	data = data[:total]
+	for off+8 <= len(data) {
+		typ := binary.LittleEndian.Uint16(data[off:])
+		headerSize := int(binary.LittleEndian.Uint16(data[off+2:]))
+		size := int(binary.LittleEndian.Uint32(data[off+4:]))
+		if size < 8 || off+size > len(data) {
+			return nil, errors.New("清单文件数据块长度无效")
+		}
+		// 头部长度来自文件内容，必须先校验再按它切片
+		if headerSize < 8 || headerSize > size {
+			return nil, errors.New("清单文件数据块头长度无效")
+		}
+		chunk := data[off : off+size]
+		switch typ {
+		case chunkStringPool:
+			var err error
+			if strs, err = parseStringPool(chunk); err != nil {
+				return nil, err
+			}
+		case chunkResourceMap:
+			for i := headerSize; i+4 <= len(chunk); i += 4 {
+				resIDs = append(resIDs, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case chunkStartElement:
+			if err := parseElement(chunk, headerSize, strs, resIDs, info); err != nil {
+				return nil, err
+			}
+		}
+		off += size
+	}
+	if info.Package == "" {
+		return nil, errors.New("清单文件中没有包名")
+	}
+	return info, nil
+}
+
+func parseElement(chunk []byte, headerSize int, strs []string, resIDs []uint32, info *Info) error {
+	ext := chunk[headerSize:]
+	if len(ext) < 20 {
+		return errors.New("清单文件元素长度无效")
+	}
+	name := stringAt(strs, binary.LittleEndian.Uint32(ext[4:]))
+	if name != "manifest" && name != "uses-sdk" {
+		return nil
+	}
+	attrStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attrSize < 20 {
+		return errors.New("清单文件属性长度无效")
+	}
+	for i := 0; i < attrCount; i++ {
+		a := attrStart + i*attrSize
+		if a+20 > len(ext) {
+			return errors.New("清单文件属性越界")
+		}
+		attr := ext[a:]
+		nameIdx := binary.LittleEndian.Uint32(attr[4:])
+		rawValue := binary.LittleEndian.Uint32(attr[8:])
+		dataType := attr[15]
+		value := binary.LittleEndian.Uint32(attr[16:])
+
+		var resID uint32
+		if int(nameIdx) < len(resIDs) {
+			resID = resIDs[nameIdx]
+		}
+		str := func() string {
+			if dataType == typeString {
+				return stringAt(strs, value)
+			}
+			return stringAt(strs, rawValue)
+		}
+		isInt := dataType == typeIntDec || dataType == typeIntHex
+
+		switch {
+		case name == "manifest" && stringAt(strs, nameIdx) == "package" && resID == 0:
+			info.Package = str()
+		case resID == attrVersionCode && isInt:
+			info.VersionCode = int64(value)
+		case resID == attrVersionName:
+			info.VersionName = str()
+		case resID == attrMinSdkVersion && isInt:
+			info.MinSDK = int(value)
+		case resID == attrTargetSdkVersion && isInt:
+			info.TargetSDK = int(value)
+		}
+	}
+	return nil
+}
+
+func stringAt(strs []string, idx uint32) string {
+	if int64(idx) >= int64(len(strs)) {
+		return ""
+	}
+	return strs[idx]
+}
+
+// parseStringPool 解析 ResStringPool，支持 UTF-8 与 UTF-16 两种编码
+func parseStringPool(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, errors.New("字符串池长度无效")
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	flags := binary.LittleEndian.Uint32(chunk[16:])
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	utf8 := flags&(1<<8) != 0
+	if headerSize+count*4 > len(chunk) {
+		return nil, errors.New("字符串池偏移越界")
+	}
+
+	strs := make([]string, count)
+	for i := 0; i < count; i++ {
+		pos := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+		if pos >= len(chunk) {
+			return nil, errors.New("字符串偏移越界")
+		}
+		var s string
+		var err error
+		if utf8 {
+			s, err = decodeUTF8(chunk[pos:])
+		} else {
+			s, err = decodeUTF16(chunk[pos:])
+		}
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+func decodeUTF8(b []byte) (string, error) {
+	// 先是 UTF-16 字符数，再是字节数，长度超过 0x7f 时各占两个字节
+	_, n := utf8Len(b)
+	if n == 0 {
+		return "", errors.New("字符串长度无效")
+	}
+	b = b[n:]
+	length, n := utf8Len(b)
+	if n == 0 || n+length > len(b) {
+		return "", errors.New("字符串长度无效")
+	}
+	return string(b[n : n+length]), nil
+}
+
+func utf8Len(b []byte) (int, int) {
+	if len(b) < 1 {
+		return 0, 0
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1
+	}
+	if len(b) < 2 {
+		return 0, 0
+	}
+	return int(b[0]&0x7f)<<8 | int(b[1]), 2
+}
+
+func decodeUTF16(b []byte) (string, error) {
+	if len(b) < 2 {
+		return "", errors.New("字符串长度无效")
+	}
+	length := int(binary.LittleEndian.Uint16(b))
+	n := 2
+	if length&0x8000 != 0 {
+		if len(b) < 4 {
+			return "", errors.New("字符串长度无效")
+		}
+		length = (length&0x7fff)<<16 | int(binary.LittleEndian.Uint16(b[2:]))
+		n = 4
+	}
+	if n+length*2 > len(b) {
+		return "", errors.New("字符串长度无效")
+	}
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[n+i*2:])
+	}
+	return string(utf16.Decode(units)), nil
+}
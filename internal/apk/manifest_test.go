@@ -0,0 +1,214 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// 测试用清单的字符串池，前三项与资源映射一一对应
+var testStrings = []string{"versionCode", "versionName", "minSdkVersion", "package", "manifest", "uses-sdk", "com.example.app", "1.2.3"}
+
+type testAttr struct {
+	name     uint32
+	dataType byte
+	value    uint32
+}
+
+func le16(b []byte, v int) []byte    { return binary.LittleEndian.AppendUint16(b, uint16(v)) }
+func le32(b []byte, v uint32) []byte { return binary.LittleEndian.AppendUint32(b, v) }
+
+// chunk 组装一个数据块，header 为块头中类型、头长度、总长度之后的部分
+func chunk(typ int, header, body []byte) []byte {
+	b := le16(nil, typ)
+	b = le16(b, 8+len(header))
+	b = le32(b, uint32(8+len(header)+len(body)))
+	return append(append(b, header...), body...)
+}
+
+func stringPoolChunk(strs []string) []byte {
+	var offsets, data []byte
+	for _, s := range strs {
+		offsets = le32(offsets, uint32(len(data)))
+		data = append(data, byte(len(s)), byte(len(s)))
+		data = append(append(data, s...), 0)
+	}
+	header := le32(nil, uint32(len(strs)))
+	header = le32(header, 0)
+	header = le32(header, 1<<8) // UTF-8
+	header = le32(header, uint32(28+len(offsets)))
+	header = le32(header, 0)
+	return chunk(chunkStringPool, header, append(offsets, data...))
+}
+
+func resourceMapChunk(ids ...uint32) []byte {
+	var body []byte
+	for _, id := range ids {
+		body = le32(body, id)
+	}
+	return chunk(chunkResourceMap, nil, body)
+}
+
+func startElementChunk(name uint32, attrs ...testAttr) []byte {
+	header := le32(nil, 1)            // 行号
+	header = le32(header, 0xffffffff) // 注释
+	ext := le32(nil, 0xffffffff)      // 命名空间
+	ext = le32(ext, name)
+	ext = le16(ext, 20)
+	ext = le16(ext, 20)
+	ext = le16(ext, len(attrs))
+	ext = le16(ext, 0)
+	ext = le16(ext, 0)
+	ext = le16(ext, 0)
+	for _, a := range attrs {
+		ext = le32(ext, 0xffffffff)
+		ext = le32(ext, a.name)
+		raw := uint32(0xffffffff)
+		if a.dataType == typeString {
+			raw = a.value
+		}
+		ext = le32(ext, raw)
+		ext = le16(ext, 8)
+		ext = append(ext, 0, a.dataType)
+		ext = le32(ext, a.value)
+	}
+	return chunk(chunkStartElement, header, ext)
+}
+
+func xmlDoc(chunks ...[]byte) []byte {
+	return chunk(chunkXML, nil, bytes.Join(chunks, nil))
+}
+
+func testChunks() [][]byte {
+	return [][]byte{
+		stringPoolChunk(testStrings),
+		resourceMapChunk(attrVersionCode, attrVersionName, attrMinSdkVersion),
+		startElementChunk(4,
+			testAttr{3, typeString, 6},
+			testAttr{0, typeIntDec, 42},
+			testAttr{1, typeString, 7},
+		),
+		startElementChunk(5, testAttr{2, typeIntDec, 21}),
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	info, err := parseManifest(xmlDoc(testChunks()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Info{Package: "com.example.app", VersionCode: 42, VersionName: "1.2.3", MinSDK: 21}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestParseManifestTruncated(t *testing.T) {
+	data := xmlDoc(testChunks()...)
+	for n := 0; n < len(data); n++ {
+		if _, err := parseManifest(data[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: expected error", n)
+		}
+	}
+}
+
+func TestParseManifestCorrupt(t *testing.T) {
+	const elem = 2 // testChunks 中 manifest 元素的位置
+	tests := []struct {
+		name    string
+		corrupt func(chunks [][]byte) []byte
+	}{
+		{"not binary xml", func(c [][]byte) []byte { return []byte("<manifest package=\"x\"/>") }},
+		{"root header too small", func(c [][]byte) []byte {
+			d := xmlDoc(c...)
+			binary.LittleEndian.PutUint16(d[2:], 0)
+			return d
+		}},
+		{"element header larger than chunk", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint16(c[elem][2:], 0xffff)
+			return xmlDoc(c...)
+		}},
+		{"element header smaller than chunk header", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint16(c[elem][2:], 4)
+			return xmlDoc(c...)
+		}},
+		{"resource map header larger than chunk", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint16(c[1][2:], 0x100)
+			return xmlDoc(c...)
+		}},
+		{"chunk size zero", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint32(c[elem][4:], 0)
+			return xmlDoc(c...)
+		}},
+		{"chunk size past end", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint32(c[elem][4:], 0xffffffff)
+			return xmlDoc(c...)
+		}},
+		{"attribute count past end", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint16(c[elem][16+12:], 0xffff)
+			return xmlDoc(c...)
+		}},
+		{"attribute size too small", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint16(c[elem][16+10:], 4)
+			return xmlDoc(c...)
+		}},
+		{"string count past end", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint32(c[0][8:], 0xffffff)
+			return xmlDoc(c...)
+		}},
+		{"string offset past end", func(c [][]byte) []byte {
+			binary.LittleEndian.PutUint32(c[0][28:], 0xffffff)
+			return xmlDoc(c...)
+		}},
+		{"string length past end", func(c [][]byte) []byte {
+			pos := 28 + 4*len(testStrings)
+			c[0][pos], c[0][pos+1] = 0x7f, 0x7f
+			return xmlDoc(c...)
+		}},
+		{"no package", func(c [][]byte) []byte { return xmlDoc(c[0], c[1], c[3]) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if info, err := parseManifest(tt.corrupt(testChunks())); err == nil {
+				t.Errorf("expected error, got %+v", info)
+			}
+		})
+	}
+}
+
+func FuzzParseManifest(f *testing.F) {
+	f.Add(xmlDoc(testChunks()...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parseManifest(data)
+	})
+}
+
+func TestParseZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string][]byte{
+		"AndroidManifest.xml":       xmlDoc(testChunks()...),
+		"lib/arm64-v8a/libgame.so":  nil,
+		"lib/x86_64/libgame.so":     nil,
+		"lib/arm64-v8a/sub/libx.so": nil,
+		"assets/lib/armeabi-v7a.so": nil,
+		"classes.dex":               nil,
+	} {
+		w, _ := zw.Create(name)
+		w.Write(content)
+	}
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := parseZip(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"arm64-v8a", "x86_64"}; !reflect.DeepEqual(info.ABIs, want) {
+		t.Errorf("ABIs = %v, want %v", info.ABIs, want)
+	}
+}
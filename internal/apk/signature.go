@@ -0,0 +1,269 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// APK 签名块中各签名方案的 ID
+const (
+	sigSchemeV2 = 0x7109871a
+	sigSchemeV3 = 0xf05368c0
+)
+
+var sigBlockMagic = []byte("APK Sig Block 42")
+
+// Signers 返回 APK 签名证书的 SHA-256 指纹（小写十六进制，已排序）。
+// 优先读取 v3、v2 签名块，没有签名块时回退到 v1（META-INF 下的 PKCS#7 签名文件）。
+func Signers(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := signingBlock(f, fi.Size())
+	if err != nil {
+		return nil, err
+	}
+	if block != nil {
+		for _, id := range []uint32{sigSchemeV3, sigSchemeV2} {
+			if v, ok := block[id]; ok {
+				certs, err := schemeCerts(v)
+				if err != nil {
+					return nil, fmt.Errorf("解析签名块失败: %w", err)
+				}
+				return fingerprints(certs), nil
+			}
+		}
+	}
+
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return nil, err
+	}
+	certs, err := v1Certs(zr)
+	if err != nil {
+		return nil, err
+	}
+	return fingerprints(certs), nil
+}
+
+func fingerprints(certs [][]byte) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, c := range certs {
+		sum := sha256.Sum256(c)
+		fp := hex.EncodeToString(sum[:])
+		if !seen[fp] {
+			seen[fp] = true
+			list = append(list, fp)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// signingBlock 定位位于中央目录之前的 APK 签名块，返回 ID 到内容的映射；没有签名块时返回 nil
+func signingBlock(r io.ReaderAt, size int64) (map[uint32][]byte, error) {
+	cdOffset, err := centralDirectoryOffset(r, size)
+	if err != nil {
+		return nil, err
+	}
+	if cdOffset < 32 {
+		return nil, nil
+	}
+	footer := make([]byte, 24)
+	if _, err := r.ReadAt(footer, cdOffset-24); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(footer[8:], sigBlockMagic) {
+		return nil, nil
+	}
+	blockSize := int64(binary.LittleEndian.Uint64(footer))
+	start := cdOffset - blockSize - 8
+	if blockSize < 24 || blockSize > 64<<20 || start < 0 {
+		return nil, errors.New("签名块长度无效")
+	}
+	buf := make([]byte, blockSize-24)
+	if _, err := r.ReadAt(buf, start+8); err != nil {
+		return nil, err
+	}
+
+	pairs := make(map[uint32][]byte)
+	for len(buf) > 0 {
+		if len(buf) < 12 {
+			return nil, errors.New("签名块数据无效")
+		}
+		n := binary.LittleEndian.Uint64(buf)
+		if n < 4 || n > uint64(len(buf)-8) {
+			return nil, errors.New("签名块数据无效")
+		}
+		pairs[binary.LittleEndian.Uint32(buf[8:])] = buf[12 : 8+n]
+		buf = buf[8+n:]
+	}
+	return pairs, nil
+}
+
+// centralDirectoryOffset 从 ZIP 的中央目录结束记录（EOCD）中读取中央目录偏移
+func centralDirectoryOffset(r io.ReaderAt, size int64) (int64, error) {
+	// EOCD 固定部分 22 字节，之后是最长 65535 字节的注释
+	n := int64(22 + 65535)
+	if n > size {
+		n = size
+	}
+	buf := make([]byte, n)
+	if _, err := r.ReadAt(buf, size-n); err != nil {
+		return 0, err
+	}
+	for i := len(buf) - 22; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(buf[i:]) == 0x06054b50 {
+			return int64(binary.LittleEndian.Uint32(buf[i+16:])), nil
+		}
+	}
+	return 0, errors.New("找不到 ZIP 中央目录")
+}
+
+// schemeCerts 解析 v2/v3 签名方案的内容，返回每个签名者的首个（即签名用的）证书
+func schemeCerts(v []byte) ([][]byte, error) {
+	signers, err := lengthPrefixed(v)
+	if err != nil {
+		return nil, err
+	}
+	var certs [][]byte
+	for len(signers) > 0 {
+		var signer, signedData, certList []byte
+		if signer, signers, err = nextPrefixed(signers); err != nil {
+			return nil, err
+		}
+		if signedData, _, err = nextPrefixed(signer); err != nil {
+			return nil, err
+		}
+		// signed data 依次为 digests、certificates
+		if _, signedData, err = nextPrefixed(signedData); err != nil {
+			return nil, err
+		}
+		if certList, _, err = nextPrefixed(signedData); err != nil {
+			return nil, err
+		}
+		cert, _, err := nextPrefixed(certList)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("签名块中没有签名者")
+	}
+	return certs, nil
+}
+
+func lengthPrefixed(b []byte) ([]byte, error) {
+	v, _, err := nextPrefixed(b)
+	return v, err
+}
+
+// nextPrefixed 读取一个 uint32 长度前缀的字段，返回字段内容和剩余数据
+func nextPrefixed(b []byte) ([]byte, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("签名数据被截断")
+	}
+	n := binary.LittleEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, errors.New("签名数据被截断")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// v1Certs 从 META-INF 下的 .RSA/.DSA/.EC 签名文件中取出签名证书（证书链中的末端证书）
+func v1Certs(zr *zip.Reader) ([][]byte, error) {
+	var certs [][]byte
+	for _, f := range zr.File {
+		dir, name := path.Split(f.Name)
+		ext := strings.ToUpper(path.Ext(name))
+		if dir != "META-INF/" || (ext != ".RSA" && ext != ".DSA" && ext != ".EC") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, 1<<20))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		leaf, err := pkcs7LeafCerts(data)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", f.Name, err)
+		}
+		certs = append(certs, leaf...)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("APK 未签名")
+	}
+	return certs, nil
+}
+
+func pkcs7LeafCerts(data []byte) ([][]byte, error) {
+	var ci pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, err
+	}
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	// 不是其他证书签发者的证书即为末端证书
+	var leaf [][]byte
+	for _, c := range parsed {
+		issuer := false
+		for _, o := range parsed {
+			if o != c && bytes.Equal(o.RawIssuer, c.RawSubject) {
+				issuer = true
+				break
+			}
+		}
+		if !issuer {
+			leaf = append(leaf, c.Raw)
+		}
+	}
+	if len(leaf) == 0 {
+		return nil, errors.New("签名文件中没有证书")
+	}
+	return leaf, nil
+}
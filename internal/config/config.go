@@ -9,38 +9,83 @@ import (
 	"path/filepath"
 )
 
-// LauncherConfig 描述如何从源页面发现启动器的 GitHub 仓库 URL。
+// LauncherConfig 描述启动器的上游来源。Type 为空或 "github" 时，从源页面发现启动器的 GitHub 仓库 URL；
+// Type 为 "gitlab" 时，SourceURL 是项目地址（如 https://gitlab.com/group/project），BaseURL 可指定部署在子路径下的自建实例，
+// Token 为访问私有项目的令牌。
+// Type 为 "gitea" 或 "forgejo" 时，SourceURL 是仓库地址（如 https://codeberg.org/owner/repo），BaseURL 与 Token 的含义同上。
+// Type 为 "direct" 时，SourceURL 是单个文件的直链，文件的 Last-Modified/ETag 变化即视为新版本。
 // 如果 RepoSelector 以 "regex:" 开头，它将被视为正则表达式来匹配锚点 href。
 // 如果 RepoSelector 为空，则使用第一个包含 "github.com" 的锚点 href。
 // SourceURL 可以直接是 GitHub 仓库 URL（例如 https://github.com/owner/repo），在这种情况下选择器被忽略。
+// KeepHistory 大于 0 时，除最新版本外还会镜像最近的 N 个 release（包含最新版本），缺失的版本会在下次扫描时补齐。
+// CheckCron 非空时该启动器使用独立的检查计划，不再参与全局 check_cron 的扫描。
+// BetaChannel 为 true 时额外镜像 GitHub 上的预发布版本，并在 index.json 中记录为 beta 通道。
+// SourceArchives 为 true 时同时镜像 release 的源码压缩包（zip / tar.gz），在 index.json 中标记为 kind: source。
+// IncludeAssets/ExcludeAssets 按文件名过滤要镜像的资源，规则为通配符（如 *.apk），以 "regex:" 开头时视为正则表达式。
+// Segments 大于 1 时，大小不小于 SegmentThresholdMB（默认 64）MB 且上游支持 Range 的资源分成 Segments 段并行下载。
 
 type LauncherConfig struct {
-	Name         string `json:"name"`
-	SourceURL    string `json:"source_url"`
-	RepoSelector string `json:"repo_selector"`
+	Name               string   `json:"name"`
+	Type               string   `json:"type,omitempty"`
+	SourceURL          string   `json:"source_url"`
+	RepoSelector       string   `json:"repo_selector"`
+	BaseURL            string   `json:"base_url,omitempty"`
+	Token              string   `json:"token,omitempty"`
+	KeepHistory        int      `json:"keep_history,omitempty"`
+	BetaChannel        bool     `json:"beta_channel,omitempty"`
+	CheckCron          string   `json:"check_cron,omitempty"`
+	SourceArchives     bool     `json:"source_archives,omitempty"`
+	IncludeAssets      []string `json:"include_assets,omitempty"`
+	ExcludeAssets      []string `json:"exclude_assets,omitempty"`
+	Segments           int      `json:"segments,omitempty"`
+	SegmentThresholdMB int      `json:"segment_threshold_mb,omitempty"`
+}
+
+// GitHubAppConfig 是 GitHub App 凭据，PrivateKeyPath 为相对项目根目录或绝对路径的 PEM 私钥文件
+type GitHubAppConfig struct {
+	AppID          int64  `json:"app_id"`
+	InstallationID int64  `json:"installation_id"`
+	PrivateKeyPath string `json:"private_key_path"`
+}
+
+// AcceleratorConfig 是下载 GitHub 资源时的一种加速方式。Type 为 "direct"（直连）、"xget"（URL 为 Xget 域名）
+// 或 "prefix"（URL 为代理前缀，与 asset_proxy_url 相同），Name 为空时使用 Type 与 URL 生成。
+type AcceleratorConfig struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
 }
 
 type Config struct {
-	ServerAddress          string           `json:"server_address"`
-	ServerPort             int              `json:"server_port"`
-	CheckCron              string           `json:"check_cron"`
-	StoragePath            string           `json:"storage_path"`
-	GitHubToken            string           `json:"github_token"`
-	AdminUser              string           `json:"admin_user"`
-	AdminPassword          string           `json:"admin_password"`
-	AdminEnabled           bool             `json:"admin_enabled"`
-	AdminMaxRetries        int              `json:"admin_max_retries"`
-	AdminLockDuration      int              `json:"admin_lock_duration"` // 单位：分钟
-	ProxyURL               string           `json:"proxy_url"`
-	AssetProxyURL          string           `json:"asset_proxy_url"`
-	XgetDomain             string           `json:"xget_domain"`
-	XgetEnabled            bool             `json:"xget_enabled"`
-	DownloadTimeoutMinutes int              `json:"download_timeout_minutes"`
-	ConcurrentDownloads    int              `json:"concurrent_downloads"`
-	DownloadUrlBase        string           `json:"download_url_base,omitempty"`
-	TwoFactorEnabled       bool             `json:"two_factor_enabled"`
-	TwoFactorSecret        string           `json:"two_factor_secret"`
-	Launchers              []LauncherConfig `json:"launchers"`
+	ServerAddress          string              `json:"server_address"`
+	ServerPort             int                 `json:"server_port"`
+	CheckCron              string              `json:"check_cron"`
+	StoragePath            string              `json:"storage_path"`
+	GitHubToken            string              `json:"github_token"`
+	GitHubTokens           []string            `json:"github_tokens,omitempty"` // 额外的令牌，与 github_token 组成令牌池
+	GitHubApp              *GitHubAppConfig    `json:"github_app,omitempty"`
+	GitHubWebhookSecret    string              `json:"github_webhook_secret,omitempty"`
+	AdminUser              string              `json:"admin_user"`
+	AdminPassword          string              `json:"admin_password"`
+	AdminEnabled           bool                `json:"admin_enabled"`
+	AdminMaxRetries        int                 `json:"admin_max_retries"`
+	AdminLockDuration      int                 `json:"admin_lock_duration"` // 单位：分钟
+	ProxyURL               string              `json:"proxy_url"`
+	AssetProxyURL          string              `json:"asset_proxy_url"`
+	XgetDomain             string              `json:"xget_domain"`
+	XgetEnabled            bool                `json:"xget_enabled"`
+	Accelerators           []AcceleratorConfig `json:"accelerators,omitempty"` // 按顺序尝试的加速方式，为空时由 asset_proxy_url、xget 配置生成
+	DownloadTimeoutMinutes int                 `json:"download_timeout_minutes"`
+	ConcurrentDownloads    int                 `json:"concurrent_downloads"`             // 全部启动器共享的同时下载任务数
+	PerHostDownloads       int                 `json:"per_host_downloads,omitempty"`     // 同一上游主机同时下载的任务数，0 表示不限制
+	DownloadRateLimitKB    int                 `json:"download_rate_limit_kb,omitempty"` // 从上游下载的总速率上限（KB/s），0 表示不限制
+	DownloadWindows        []string            `json:"download_windows,omitempty"`       // 允许下载大文件的时间段（本地时间），如 "02:00-07:00"
+	DownloadWindowMinMB    int                 `json:"download_window_min_mb,omitempty"` // 受下载窗口限制的最小文件大小，0 表示全部资源
+	DownloadUrlBase        string              `json:"download_url_base,omitempty"`
+	TrustedProxies         []string            `json:"trusted_proxies,omitempty"` // 可信反向代理的 IP 或 CIDR，只采用来自这些地址的 X-Forwarded-Host/Proto
+	TwoFactorEnabled       bool                `json:"two_factor_enabled"`
+	TwoFactorSecret        string              `json:"two_factor_secret"`
+	Launchers              []LauncherConfig    `json:"launchers"`
 }
 
 func LoadConfig(projectRoot string) (*Config, error) {
@@ -89,6 +134,25 @@ func LoadConfig(projectRoot string) (*Config, error) {
 	return &cfg, nil
 }
 
+// GitHubTokenList 返回 github_token 与 github_tokens 合并后的令牌列表
+func (c *Config) GitHubTokenList() []string {
+	var tokens []string
+	if c.GitHubToken != "" {
+		tokens = append(tokens, c.GitHubToken)
+	}
+	return append(tokens, c.GitHubTokens...)
+}
+
+// Launcher 按名称查找启动器配置
+func (c *Config) Launcher(name string) (LauncherConfig, bool) {
+	for _, l := range c.Launchers {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return LauncherConfig{}, false
+}
+
 func (c *Config) Save(projectRoot string) error {
 	cfgPath := filepath.Join(projectRoot, "config.json")
 	b, err := json.MarshalIndent(c, "", "  ")
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -81,6 +82,64 @@ func createTables() error {
             key TEXT PRIMARY KEY,
             value TEXT,
             created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE TABLE IF NOT EXISTS http_cache (
+            url TEXT PRIMARY KEY,
+            etag TEXT,
+            last_modified TEXT,
+            header TEXT,
+            body BLOB,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE TABLE IF NOT EXISTS apk_signers (
+            launcher TEXT,
+            package TEXT,
+            signers TEXT,
+            version TEXT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (launcher, package)
+        )`,
+		`CREATE TABLE IF NOT EXISTS accelerator_stats (
+            name TEXT PRIMARY KEY,
+            success_rate REAL,
+            throughput REAL,
+            successes INTEGER DEFAULT 0,
+            failures INTEGER DEFAULT 0,
+            last_error TEXT,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE TABLE IF NOT EXISTS download_jobs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            launcher TEXT,
+            version TEXT,
+            asset TEXT,
+            url TEXT,
+            size INTEGER DEFAULT 0,
+            kind TEXT DEFAULT '',
+            state TEXT DEFAULT 'pending',
+            attempts INTEGER DEFAULT 0,
+            next_run_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            last_error TEXT DEFAULT '',
+            sha256 TEXT DEFAULT '',
+            sha1 TEXT DEFAULT '',
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            UNIQUE (launcher, version, asset)
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_download_jobs_state ON download_jobs(state, next_run_at)`,
+		`CREATE TABLE IF NOT EXISTS download_releases (
+            launcher TEXT,
+            version TEXT,
+            release_id INTEGER DEFAULT 0,
+            tag_name TEXT DEFAULT '',
+            name TEXT DEFAULT '',
+            body TEXT DEFAULT '',
+            published_at DATETIME,
+            prerelease INTEGER DEFAULT 0,
+            is_latest INTEGER DEFAULT 0,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (launcher, version)
         )`,
 		`CREATE INDEX IF NOT EXISTS idx_visits_created_at ON visits(created_at)`,
 		`CREATE INDEX IF NOT EXISTS idx_downloads_created_at ON downloads(created_at)`,
@@ -141,3 +200,348 @@ func GetIPBlacklist() ([]map[string]string, error) {
 	}
 	return list, nil
 }
+
+// HTTPCacheEntry 是上游 API 响应的缓存，用于发起条件请求（If-None-Match / If-Modified-Since）
+type HTTPCacheEntry struct {
+	ETag         string
+	LastModified string
+	Header       string // JSON 编码的响应头
+	Body         []byte
+}
+
+func GetHTTPCache(url string) (*HTTPCacheEntry, error) {
+	var e HTTPCacheEntry
+	err := DB.QueryRow("SELECT etag, last_modified, header, body FROM http_cache WHERE url = ?", url).Scan(&e.ETag, &e.LastModified, &e.Header, &e.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func SaveHTTPCache(url string, e *HTTPCacheEntry) error {
+	_, err := DB.Exec("INSERT OR REPLACE INTO http_cache (url, etag, last_modified, header, body, updated_at) VALUES (?, ?, ?, ?, ?, datetime('now'))",
+		url, e.ETag, e.LastModified, e.Header, e.Body)
+	return err
+}
+
+// GetAPKSigners 返回启动器某个包名已知的签名证书指纹（逗号分隔），未记录时返回 sql.ErrNoRows
+func GetAPKSigners(launcher, pkg string) (string, error) {
+	var signers string
+	err := DB.QueryRow("SELECT signers FROM apk_signers WHERE launcher = ? AND package = ?", launcher, pkg).Scan(&signers)
+	return signers, err
+}
+
+// HasAPKSigners 判断启动器是否已记录过任一包名的签名证书
+func HasAPKSigners(launcher string) (bool, error) {
+	var exists bool
+	err := DB.QueryRow("SELECT EXISTS(SELECT 1 FROM apk_signers WHERE launcher = ?)", launcher).Scan(&exists)
+	return exists, err
+}
+
+// SaveAPKSigners 记录包名首次出现时的签名证书指纹，已有记录时不覆盖
+func SaveAPKSigners(launcher, pkg, signers, version string) error {
+	_, err := DB.Exec("INSERT OR IGNORE INTO apk_signers (launcher, package, signers, version) VALUES (?, ?, ?, ?)",
+		launcher, pkg, signers, version)
+	return err
+}
+
+// AcceleratorStats 是下载加速方式的健康记录。SuccessRate 与 Throughput（字节/秒）为指数加权移动平均
+type AcceleratorStats struct {
+	Name        string    `json:"name"`
+	SuccessRate float64   `json:"success_rate"`
+	Throughput  float64   `json:"throughput"`
+	Successes   int64     `json:"successes"`
+	Failures    int64     `json:"failures"`
+	LastError   string    `json:"last_error"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// accelEWMAWeight 是每次新结果在移动平均中的权重
+const accelEWMAWeight = 0.3
+
+func GetAcceleratorStats() (map[string]*AcceleratorStats, error) {
+	rows, err := DB.Query("SELECT name, success_rate, throughput, successes, failures, COALESCE(last_error, ''), updated_at FROM accelerator_stats")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := make(map[string]*AcceleratorStats)
+	for rows.Next() {
+		var st AcceleratorStats
+		if err := rows.Scan(&st.Name, &st.SuccessRate, &st.Throughput, &st.Successes, &st.Failures, &st.LastError, &st.UpdatedAt); err != nil {
+			return nil, err
+		}
+		stats[st.Name] = &st
+	}
+	return stats, rows.Err()
+}
+
+// RecordAcceleratorResult 记录一次下载结果，throughput 仅在成功时计入。
+// 没有记录的加速方式视为健康度为 1，首次结果同样按移动平均计入，避免一次失败就被排到最后。
+func RecordAcceleratorResult(name string, ok bool, throughput float64, errMsg string) error {
+	success := 0.0
+	if ok {
+		success = 1
+	}
+	_, err := DB.Exec(`INSERT INTO accelerator_stats (name, success_rate, throughput, successes, failures, last_error, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+        ON CONFLICT(name) DO UPDATE SET
+            success_rate = success_rate * ? + ?,
+            throughput = CASE WHEN excluded.throughput <= 0 THEN throughput
+                WHEN throughput > 0 THEN throughput * ? + excluded.throughput * ?
+                ELSE excluded.throughput END,
+            successes = successes + excluded.successes,
+            failures = failures + excluded.failures,
+            last_error = CASE WHEN excluded.failures > 0 THEN excluded.last_error ELSE last_error END,
+            updated_at = excluded.updated_at`,
+		name, 1-accelEWMAWeight+accelEWMAWeight*success, throughput, boolInt(ok), boolInt(!ok), errMsg,
+		1-accelEWMAWeight, accelEWMAWeight*success,
+		1-accelEWMAWeight, accelEWMAWeight)
+	return err
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 下载任务状态
+const (
+	JobPending = "pending" // 等待下载（包括失败后等待重试）
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed" // 重试次数用尽，需要手动重试
+)
+
+// DownloadJob 是单个资源的下载任务，记录了下载该资源所需的全部信息，进程重启后可独立执行
+type DownloadJob struct {
+	ID       int64  `json:"id"`
+	Launcher string `json:"launcher"`
+	Version  string `json:"version"`
+	Asset    string `json:"asset"`
+	URL      string `json:"url"`
+	Size     int    `json:"size"`
+	Kind     string `json:"kind,omitempty"`
+	// 下载时附带的请求头可能包含访问令牌，不随任务保存，由下载器按启动器配置重新生成
+	State     string    `json:"state"`
+	Attempts  int       `json:"attempts"`
+	NextRunAt time.Time `json:"next_run_at"`
+	LastError string    `json:"last_error"`
+	SHA256    string    `json:"sha256,omitempty"`
+	SHA1      string    `json:"sha1,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const jobColumns = "id, launcher, version, asset, url, size, kind, state, attempts, next_run_at, last_error, sha256, sha1, created_at, updated_at"
+
+func scanJobs(rows *sql.Rows) ([]DownloadJob, error) {
+	defer rows.Close()
+	jobs := []DownloadJob{}
+	for rows.Next() {
+		var j DownloadJob
+		if err := rows.Scan(&j.ID, &j.Launcher, &j.Version, &j.Asset, &j.URL, &j.Size, &j.Kind, &j.State, &j.Attempts, &j.NextRunAt, &j.LastError, &j.SHA256, &j.SHA1, &j.CreatedAt, &j.UpdatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// EnqueueJob 为资源创建下载任务，任务已存在时只更新下载信息，保留其状态和重试记录
+func EnqueueJob(j DownloadJob) error {
+	_, err := DB.Exec(`INSERT INTO download_jobs (launcher, version, asset, url, size, kind) VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT(launcher, version, asset) DO UPDATE SET url = excluded.url, size = excluded.size, kind = excluded.kind`,
+		j.Launcher, j.Version, j.Asset, j.URL, j.Size, j.Kind)
+	return err
+}
+
+// QueuedRelease 是等待下载完成后发布的 release，与其下载任务一同持久化
+type QueuedRelease struct {
+	Launcher    string
+	Version     string
+	ReleaseID   int64
+	TagName     string
+	Name        string
+	Body        string
+	PublishedAt time.Time
+	Prerelease  bool
+	IsLatest    bool
+}
+
+const releaseColumns = "launcher, version, release_id, tag_name, name, body, published_at, prerelease, is_latest"
+
+func scanRelease(row interface{ Scan(...any) error }) (*QueuedRelease, error) {
+	var r QueuedRelease
+	var publishedAt sql.NullTime
+	if err := row.Scan(&r.Launcher, &r.Version, &r.ReleaseID, &r.TagName, &r.Name, &r.Body, &publishedAt, &r.Prerelease, &r.IsLatest); err != nil {
+		return nil, err
+	}
+	r.PublishedAt = publishedAt.Time
+	return &r, nil
+}
+
+// SaveRelease 登记等待发布的 release。重复登记时更新发布信息，但不会取消 latest 标记（由 DemoteLatestReleases 负责）
+func SaveRelease(r *QueuedRelease) error {
+	_, err := DB.Exec(`INSERT INTO download_releases (`+releaseColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(launcher, version) DO UPDATE SET release_id = excluded.release_id, tag_name = excluded.tag_name,
+            name = excluded.name, body = excluded.body, published_at = excluded.published_at, prerelease = excluded.prerelease,
+            is_latest = MAX(is_latest, excluded.is_latest), updated_at = datetime('now')`,
+		r.Launcher, r.Version, r.ReleaseID, r.TagName, r.Name, r.Body, r.PublishedAt.UTC(), boolInt(r.Prerelease), boolInt(r.IsLatest))
+	return err
+}
+
+// GetRelease 返回等待发布的 release，未登记时返回 sql.ErrNoRows
+func GetRelease(launcher, version string) (*QueuedRelease, error) {
+	return scanRelease(DB.QueryRow("SELECT "+releaseColumns+" FROM download_releases WHERE launcher = ? AND version = ?", launcher, version))
+}
+
+// ListReleases 返回全部等待发布的 release
+func ListReleases() ([]*QueuedRelease, error) {
+	rows, err := DB.Query("SELECT " + releaseColumns + " FROM download_releases ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*QueuedRelease
+	for rows.Next() {
+		r, err := scanRelease(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// DemoteLatestReleases 取消启动器除 version 外其他等待发布的 release 的 latest 标记，返回被取消的版本
+func DemoteLatestReleases(launcher, version string) ([]string, error) {
+	rows, err := DB.Query("UPDATE download_releases SET is_latest = 0, updated_at = datetime('now') WHERE launcher = ? AND version != ? AND is_latest = 1 RETURNING version", launcher, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// DeleteOrphanJobs 删除没有对应 release 记录、无法发布的下载任务，返回删除的任务数
+func DeleteOrphanJobs() (int64, error) {
+	res, err := DB.Exec(`DELETE FROM download_jobs WHERE NOT EXISTS (
+        SELECT 1 FROM download_releases r WHERE r.launcher = download_jobs.launcher AND r.version = download_jobs.version)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ResetRunningJobs 将上次进程退出时仍在下载的任务恢复为等待状态
+func ResetRunningJobs() error {
+	_, err := DB.Exec("UPDATE download_jobs SET state = ?, updated_at = datetime('now') WHERE state = ?", JobPending, JobRunning)
+	return err
+}
+
+// DueJobs 返回已到执行时间的等待任务，按计划时间排序
+func DueJobs() ([]DownloadJob, error) {
+	rows, err := DB.Query("SELECT "+jobColumns+" FROM download_jobs WHERE state = ? AND next_run_at <= datetime('now') ORDER BY next_run_at, id", JobPending)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// VersionJobs 返回某个版本的全部下载任务
+func VersionJobs(launcher, version string) ([]DownloadJob, error) {
+	rows, err := DB.Query("SELECT "+jobColumns+" FROM download_jobs WHERE launcher = ? AND version = ? ORDER BY id", launcher, version)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// ListJobs 返回下载任务，state 为空时返回全部状态
+func ListJobs(state string, limit int) ([]DownloadJob, error) {
+	query := "SELECT " + jobColumns + " FROM download_jobs"
+	var args []interface{}
+	if state != "" {
+		query += " WHERE state = ?"
+		args = append(args, state)
+	}
+	query += " ORDER BY updated_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+// StartJob 将任务标记为下载中并增加尝试次数
+func StartJob(id int64) error {
+	_, err := DB.Exec("UPDATE download_jobs SET state = ?, attempts = attempts + 1, updated_at = datetime('now') WHERE id = ?", JobRunning, id)
+	return err
+}
+
+// FinishJob 记录任务成功及下载文件的摘要
+func FinishJob(id int64, sha256, sha1 string) error {
+	_, err := DB.Exec("UPDATE download_jobs SET state = ?, last_error = '', sha256 = ?, sha1 = ?, updated_at = datetime('now') WHERE id = ?",
+		JobDone, sha256, sha1, id)
+	return err
+}
+
+// FailJob 记录任务失败。state 为 JobPending 时任务在 retryAfter 之后重试
+func FailJob(id int64, state string, retryAfter time.Duration, errMsg string) error {
+	_, err := DB.Exec("UPDATE download_jobs SET state = ?, next_run_at = datetime('now', ?), last_error = ?, updated_at = datetime('now') WHERE id = ?",
+		state, fmt.Sprintf("+%d seconds", int64(retryAfter.Seconds())), errMsg, id)
+	return err
+}
+
+// PauseJob 将下载中的任务恢复为等待状态，本次不计入尝试次数
+func PauseJob(id int64, errMsg string) error {
+	_, err := DB.Exec("UPDATE download_jobs SET state = ?, attempts = MAX(attempts - 1, 0), last_error = ?, updated_at = datetime('now') WHERE id = ?",
+		JobPending, errMsg, id)
+	return err
+}
+
+// RetryJob 立即重试任务并清零尝试次数，id 为 0 时重试全部失败的任务。返回受影响的任务数
+func RetryJob(id int64) (int64, error) {
+	query := "UPDATE download_jobs SET state = ?, attempts = 0, next_run_at = datetime('now'), updated_at = datetime('now') WHERE "
+	args := []interface{}{JobPending}
+	if id == 0 {
+		query += "state = ?"
+		args = append(args, JobFailed)
+	} else {
+		query += "id = ? AND state != ?"
+		args = append(args, id, JobRunning)
+	}
+	res, err := DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteVersionJobs 删除某个版本的全部下载任务及其 release 记录（版本发布或被隔离后调用）
+func DeleteVersionJobs(launcher, version string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM download_jobs WHERE launcher = ? AND version = ?", launcher, version); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM download_releases WHERE launcher = ? AND version = ?", launcher, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,109 @@
+package downloader
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/db"
+)
+
+// 加速方式类型
+const (
+	AccelDirect = "direct"
+	AccelXget   = "xget"
+	AccelPrefix = "prefix"
+)
+
+const githubPrefix = "https://github.com/"
+
+// accelRecoveryHalfLife 是加速方式失败后健康度恢复一半所需的时间，使一度故障的加速方式之后仍有机会被重新尝试
+const accelRecoveryHalfLife = 6 * time.Hour
+
+// Accelerator 是下载 GitHub 资源时的一种加速方式
+type Accelerator struct {
+	Name string
+	Type string
+	URL  string
+}
+
+// Rewrite 返回经该加速方式改写后的下载地址，只有 GitHub 地址会被改写
+func (a Accelerator) Rewrite(u string) string {
+	if !strings.HasPrefix(u, githubPrefix) {
+		return u
+	}
+	switch a.Type {
+	case AccelXget:
+		return strings.TrimRight(a.URL, "/") + "/gh/" + strings.TrimPrefix(u, githubPrefix)
+	case AccelPrefix:
+		return a.URL + u
+	}
+	return u
+}
+
+// candidate 是一次下载中可尝试的下载地址
+type candidate struct {
+	accel string // 加速方式名称，非 GitHub 地址为空（不记录健康度）
+	url   string
+}
+
+// downloadCandidates 按健康度排序返回资源的下载地址。非 GitHub 地址只有直连一种方式；
+// 健康度相同时保持配置顺序。
+func downloadCandidates(downloadURL string, accels []Accelerator) []candidate {
+	if !strings.HasPrefix(downloadURL, githubPrefix) || len(accels) == 0 {
+		return []candidate{{url: downloadURL}}
+	}
+	stats, err := db.GetAcceleratorStats()
+	if err != nil {
+		log.Printf("读取加速方式健康度失败: %v", err)
+	}
+	type scored struct {
+		candidate
+		health     float64
+		throughput float64
+	}
+	list := make([]scored, 0, len(accels))
+	for _, a := range accels {
+		c := scored{candidate: candidate{accel: a.Name, url: a.Rewrite(downloadURL)}, health: 1}
+		if st, ok := stats[a.Name]; ok {
+			// 健康度随时间向 1 恢复
+			elapsed := time.Since(st.UpdatedAt)
+			c.health = 1 - (1-st.SuccessRate)*math.Pow(0.5, float64(elapsed)/float64(accelRecoveryHalfLife))
+			c.throughput = st.Throughput
+		}
+		list = append(list, c)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		// 健康度按 0.1 分档，同档内吞吐量高者优先
+		hi, hj := math.Round(list[i].health*10), math.Round(list[j].health*10)
+		if hi != hj {
+			return hi > hj
+		}
+		return list[i].throughput > list[j].throughput
+	})
+	candidates := make([]candidate, len(list))
+	for i, c := range list {
+		candidates[i] = c.candidate
+	}
+	return candidates
+}
+
+// recordAccelResult 记录加速方式的一次下载结果
+func recordAccelResult(c candidate, err error, bytes int64, elapsed time.Duration) {
+	if c.accel == "" {
+		return
+	}
+	var throughput float64
+	var msg string
+	if err == nil && elapsed > 0 {
+		throughput = float64(bytes) / elapsed.Seconds()
+	}
+	if err != nil {
+		msg = err.Error()
+	}
+	if dbErr := db.RecordAcceleratorResult(c.accel, err == nil, throughput, msg); dbErr != nil {
+		log.Printf("记录加速方式 %s 的健康度失败: %v", c.accel, dbErr)
+	}
+}
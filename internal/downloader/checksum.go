@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ChecksumsFile 是每个版本目录下的校验和文件，格式与 sha256sum 的输出一致
+const ChecksumsFile = "SHA256SUMS"
+
+// Digests 是资源文件的摘要（小写十六进制）
+type Digests struct {
+	SHA256 string
+	SHA1   string
+}
+
+// digester 在写入数据的同时计算 SHA-256 与 SHA-1
+type digester struct {
+	sha256 hash.Hash
+	sha1   hash.Hash
+}
+
+func newDigester() *digester {
+	return &digester{sha256: sha256.New(), sha1: sha1.New()}
+}
+
+func (d *digester) Write(p []byte) (int, error) {
+	d.sha256.Write(p)
+	d.sha1.Write(p)
+	return len(p), nil
+}
+
+func (d *digester) Sum() Digests {
+	return Digests{
+		SHA256: hex.EncodeToString(d.sha256.Sum(nil)),
+		SHA1:   hex.EncodeToString(d.sha1.Sum(nil)),
+	}
+}
+
+// hashFile 计算已存在文件的摘要，用于跳过下载的文件
+func hashFile(path string) (Digests, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digests{}, err
+	}
+	defer f.Close()
+	d := newDigester()
+	if _, err := io.Copy(d, f); err != nil {
+		return Digests{}, err
+	}
+	return d.Sum(), nil
+}
+
+// writeChecksums 按资源顺序生成版本目录下的 SHA256SUMS 文件
+// 上游 release 自带同名文件时保留上游文件，不再生成。
+func writeChecksums(dir string, assets []ReleaseAssetSimple) error {
+	var b strings.Builder
+	for _, a := range assets {
+		if a.Name == ChecksumsFile {
+			return nil
+		}
+		if a.SHA256 != "" {
+			fmt.Fprintf(&b, "%s  %s\n", a.SHA256, a.Name)
+		}
+	}
+	if b.Len() == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, ChecksumsFile), []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", ChecksumsFile, err)
+	}
+	return nil
+}
+
+// ChecksumMismatchError 表示资源与上游校验和文件中记录的摘要不一致
+type ChecksumMismatchError struct {
+	Asset    string
+	Source   string // 记录该摘要的上游校验和文件
+	Expected string
+	Got      string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("资源 %s 与上游校验和 %s 不一致: 期望 %s, 实际 %s", e.Asset, e.Source, e.Expected, e.Got)
+}
+
+// bsdChecksumLine 匹配 BSD 风格的校验和行，如 "SHA256 (file.apk) = <hex>"
+var bsdChecksumLine = regexp.MustCompile(`^(?:SHA256|SHA1) \((.+)\) = ([0-9a-fA-F]+)$`)
+
+// IsChecksumFile 判断资源是否为上游发布的校验和文件（*.sha256、*.sha1、checksums.txt、SHA256SUMS 等）
+func IsChecksumFile(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".sha256", ".sha256sum", ".sha256sums", ".sha1", ".sha1sum"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	if lower == "sha256sums" || lower == "sha1sums" {
+		return true
+	}
+	return strings.HasSuffix(lower, ".txt") && (strings.Contains(lower, "checksum") || strings.Contains(lower, "sha256sum"))
+}
+
+// parseChecksums 解析校验和文件，返回文件名到摘要（小写十六进制）的映射。
+// 支持 sha256sum 格式（"<hex>  <name>" 或 "<hex> *<name>"）、BSD 格式，
+// 以及 file.apk.sha256 这类只包含摘要的单文件格式。
+func parseChecksums(fileName string, data []byte) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var sum, name string
+		if m := bsdChecksumLine.FindStringSubmatch(line); m != nil {
+			name, sum = m[1], m[2]
+		} else {
+			fields := strings.Fields(line)
+			sum = fields[0]
+			if len(fields) > 1 {
+				name = strings.Join(fields[1:], " ")
+			} else {
+				name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
+			}
+		}
+		if _, err := hex.DecodeString(sum); err != nil || (len(sum) != 64 && len(sum) != 40) {
+			continue
+		}
+		name = path.Base(strings.TrimPrefix(strings.TrimPrefix(name, "*"), "./"))
+		sums[name] = strings.ToLower(sum)
+	}
+	return sums
+}
+
+// verifyUpstreamChecksums 用上游校验和文件校验已下载的资源，只校验其中列出且已镜像的资源。
+// 摘要长度为 64 时按 SHA-256 比对，为 40 时按 SHA-1 比对。
+func verifyUpstreamChecksums(dir string, assets []ReleaseAssetSimple) error {
+	byName := make(map[string]ReleaseAssetSimple)
+	for _, a := range assets {
+		byName[a.Name] = a
+	}
+	for _, cf := range assets {
+		if !IsChecksumFile(cf.Name) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, cf.Name))
+		if err != nil {
+			return fmt.Errorf("读取上游校验和文件 %s 失败: %w", cf.Name, err)
+		}
+		verified := 0
+		for name, expected := range parseChecksums(cf.Name, data) {
+			a, ok := byName[name]
+			if !ok || a.Name == cf.Name {
+				continue
+			}
+			got := a.SHA256
+			if len(expected) == 40 {
+				got = a.SHA1
+			}
+			if got == "" {
+				continue
+			}
+			if got != expected {
+				return &ChecksumMismatchError{Asset: a.Name, Source: cf.Name, Expected: expected, Got: got}
+			}
+			verified++
+		}
+		log.Printf("已按上游校验和文件 %s 校验 %d 个资源", cf.Name, verified)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemwood_mirror/internal/source"
+)
+
+func TestFilteredChecksumFileStillVerifies(t *testing.T) {
+	initTestDB(t)
+	filter, err := NewAssetFilter([]string{"*.bin"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Filter: filter, DownloadUrlBase: "https://mirror.example.com"}
+	rel := &source.Release{TagName: "v1", Assets: []source.Asset{
+		{Name: "app.bin", DownloadURL: "https://example.com/app.bin"},
+		{Name: "SHA256SUMS", DownloadURL: "https://example.com/SHA256SUMS"},
+		{Name: "notes.md", DownloadURL: "https://example.com/notes.md"},
+	}}
+	assets := selectAssets(rel, opts)
+	if len(assets) != 2 || assets[1].Name != "SHA256SUMS" || assets[1].Kind != kindVerifyOnly {
+		t.Fatalf("selected assets = %+v, want app.bin and a verify-only SHA256SUMS", assets)
+	}
+
+	data := []byte("payload")
+	sum256, sum1 := sha256.Sum256(data), sha1.Sum(data)
+	digests := map[string]Digests{"app.bin": {SHA256: hex.EncodeToString(sum256[:]), SHA1: hex.EncodeToString(sum1[:])}}
+	base := t.TempDir()
+	staging := stagingPath(base, "fcl", "v1")
+	os.MkdirAll(staging, 0o755)
+	os.WriteFile(filepath.Join(staging, "app.bin"), data, 0o644)
+
+	// 被过滤的校验和文件仍参与校验
+	os.WriteFile(filepath.Join(staging, "SHA256SUMS"), []byte(hex.EncodeToString(make([]byte, 32))+"  app.bin\n"), 0o644)
+	_, err = publishRelease("fcl", base, rel, assets, opts, true, digests)
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want *ChecksumMismatchError", err)
+	}
+
+	os.WriteFile(filepath.Join(staging, "app.bin"), data, 0o644)
+	os.WriteFile(filepath.Join(staging, "SHA256SUMS"), []byte(digests["app.bin"].SHA256+"  app.bin\n"), 0o644)
+	indexPath, err := publishRelease("fcl", base, rel, assets, opts, true, digests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(indexPath)
+	var info ReleaseInfo
+	json.Unmarshal(b, &info)
+	if len(info.Assets) != 1 || info.Assets[0].Name != "app.bin" {
+		t.Errorf("published assets = %+v, want only app.bin", info.Assets)
+	}
+	// 发布目录中的 SHA256SUMS 由本站生成，而不是上游文件
+	sums, _ := os.ReadFile(filepath.Join(filepath.Dir(indexPath), ChecksumsFile))
+	if got := parseChecksums(ChecksumsFile, sums); len(got) != 1 || got["app.bin"] != digests["app.bin"].SHA256 {
+		t.Errorf("published %s = %q", ChecksumsFile, sums)
+	}
+}
@@ -15,62 +15,167 @@ import (
 	"sync"
 	"time"
 
-	"github.com/google/go-github/v50/github"
+	"lemwood_mirror/internal/apk"
+	"lemwood_mirror/internal/markdown"
+	"lemwood_mirror/internal/source"
 )
 
 type ReleaseInfo struct {
 	Launcher    string               `json:"launcher"`
 	TagName     string               `json:"tag_name"`
 	Name        string               `json:"name"`
+	Body        string               `json:"body,omitempty"`      // Markdown 格式的发布说明
+	BodyHTML    string               `json:"body_html,omitempty"` // 发布时渲染的安全 HTML，供下载页直接展示
 	PublishedAt time.Time            `json:"published_at"`
 	IsLatest    bool                 `json:"is_latest"`
+	Channel     string               `json:"channel"`
 	Assets      []ReleaseAssetSimple `json:"assets"`
 }
 
+// ReleaseNotesFile 是与 index.json 一同保存的发布说明文件名
+const ReleaseNotesFile = "RELEASE_NOTES.md"
+
+// 发布通道
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
 type ReleaseAssetSimple struct {
 	Name string `json:"name"`
 	URL  string `json:"url"`
 	Size int    `json:"size"`
+	Kind string `json:"kind,omitempty"` // 源码压缩包为 "source"
+	// SHA256/SHA1 是下载时计算的文件摘要（小写十六进制）
+	SHA256 string `json:"sha256,omitempty"`
+	SHA1   string `json:"sha1,omitempty"`
+	// Apk 是从 .apk 资源中解析出的包名、版本号、SDK 要求和原生库架构
+	Apk *apk.Info `json:"apk,omitempty"`
 }
 
+// Options 是一次下载任务的配置，由全局配置和启动器配置共同决定
+type Options struct {
+	ProxyURL        string
+	ServerAddress   string
+	ServerPort      int
+	DownloadUrlBase string
+	// Accelerators 是下载 GitHub 资源时依次尝试的加速方式
+	Accelerators []Accelerator
+	// Filter 决定镜像哪些资源，同时作用于下载和 index.json
+	Filter *AssetFilter
+	// SourceArchives 为 true 时同时镜像 release 的源码压缩包（不受 Filter 影响）
+	SourceArchives bool
+	// Segments 大于 1 时，大小达到 SegmentThreshold（字节）的资源按字节范围分段并行下载
+	Segments         int
+	SegmentThreshold int64
+	// AssetHeader 返回下载资源时附带的请求头（如私有实例的令牌），为 nil 时不附带
+	AssetHeader func(downloadURL string) http.Header
+	// BeforePublish 在版本目录移动到发布位置之前调用，由下载队列根据 QueueHooks 设置，返回错误时放弃发布
+	BeforePublish func() error
+}
+
+// StagingDir 是下载中的版本所在目录，位于存储根目录下，全部资源下载并校验通过后才会移动到 <launcher>/<version>
+const StagingDir = ".staging"
+
+// Downloader 在全部启动器之间共享，concurrentDownloads 是全局同时下载的任务数，
+// perHostDownloads 大于 0 时额外限制同一上游主机同时下载的任务数
 type Downloader struct {
 	httpClient *http.Client
-	semaphore  chan struct{}
+	sched      *scheduler
+	// limiter 为 nil 时不限速
+	limiter       *rateLimiter
+	windows       []Window
+	windowMinSize int64
 }
 
-func NewDownloader(timeoutMinutes, concurrentDownloads int) *Downloader {
+func NewDownloader(timeoutMinutes, concurrentDownloads, perHostDownloads int) *Downloader {
 	if concurrentDownloads <= 0 {
 		concurrentDownloads = 3 // 如果无效，默认为 3
 	}
 	return &Downloader{
-		httpClient: &http.Client{Timeout: time.Duration(timeoutMinutes) * time.Minute},
-		semaphore:  make(chan struct{}, concurrentDownloads),
+		httpClient: &http.Client{Timeout: time.Duration(timeoutMinutes) * time.Minute, CheckRedirect: source.CheckRedirect},
+		sched:      newScheduler(concurrentDownloads, perHostDownloads),
 	}
 }
 
-func (d *Downloader) DownloadLatest(ctx context.Context, launcher string, destBase string, proxyURL string, assetProxyURL string, xgetEnabled bool, xgetDomain string, rel *github.RepositoryRelease, serverAddress string, serverPort int, downloadUrlBase string, isLatest bool) (string, error) {
-	if rel == nil {
-		return "", errors.New("release 为空")
-	}
-	version := rel.GetTagName()
-	if version == "" {
-		version = rel.GetName()
-		if version == "" {
-			version = fmt.Sprintf("%d", rel.GetID())
+// kindVerifyOnly 标记被过滤规则排除、只下载用于校验其他资源的上游校验和文件，发布前会删除
+const kindVerifyOnly = "verify-only"
+
+// selectAssets 返回 release 中需要镜像的资源：经过滤规则筛选的资源，以及按需附带的源码压缩包。
+// 被过滤规则排除的上游校验和文件仍会下载，用于校验其他资源，但不会发布。
+func selectAssets(rel *source.Release, opts Options) []source.Asset {
+	var assets []source.Asset
+	for _, a := range rel.Assets {
+		if !source.ValidFileName(a.Name) {
+			// 资源名直接作为文件名，不能包含路径分隔符
+			log.Printf("资源名 %q 不是有效的文件名，跳过", a.Name)
+			continue
+		}
+		if opts.Filter.Match(a.Name) {
+			assets = append(assets, a)
+		} else if IsChecksumFile(a.Name) {
+			log.Printf("校验和文件 %s 被过滤规则排除，仅下载用于校验，不会发布", a.Name)
+			a.Kind = kindVerifyOnly
+			assets = append(assets, a)
+		} else {
+			log.Printf("资源 %s 被过滤规则排除，跳过", a.Name)
 		}
 	}
-	dir := filepath.Join(destBase, launcher, version)
+	if opts.SourceArchives {
+		assets = append(assets, rel.SourceArchives...)
+	}
+	return assets
+}
+
+// client 返回下载使用的 HTTP 客户端，配置了代理时使用独立的客户端
+func (d *Downloader) client(opts Options) (*http.Client, error) {
+	if opts.ProxyURL == "" {
+		return d.httpClient, nil
+	}
+	proxy, err := url.Parse(opts.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理URL失败: %w", err)
+	}
+	// 为代理创建新的客户端，因为默认客户端可能是共享的
+	return &http.Client{
+		Timeout:       d.httpClient.Timeout,
+		Transport:     &http.Transport{Proxy: http.ProxyURL(proxy)},
+		CheckRedirect: source.CheckRedirect,
+	}, nil
+}
+
+// stagingPath 返回版本下载中所在的暂存目录
+func stagingPath(destBase, launcher, version string) string {
+	return filepath.Join(destBase, StagingDir, launcher, version)
+}
+
+// publishRelease 在全部资源下载到暂存目录后校验资源、生成 index.json，并将暂存目录移动到发布位置。
+// assets 是该版本镜像的资源，digests 是下载时计算的各资源摘要。返回发布后的 index.json 路径。
+func publishRelease(launcher, destBase string, rel *source.Release, assets []source.Asset, opts Options, isLatest bool, digests map[string]Digests) (string, error) {
+	downloadUrlBase, serverAddress, serverPort := opts.DownloadUrlBase, opts.ServerAddress, opts.ServerPort
+	version := rel.Version()
+	if !source.ValidFileName(version) {
+		return "", fmt.Errorf("版本名 %q 不是有效的目录名", version)
+	}
+	dir := stagingPath(destBase, launcher, version)
+	// 没有资源的版本不会经过下载，暂存目录可能不存在
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", fmt.Errorf("创建目录 %s 失败: %w", dir, err)
 	}
 
 	var info ReleaseInfo
 	info.Launcher = launcher
-	info.TagName = rel.GetTagName()
-	info.Name = rel.GetName()
-	info.PublishedAt = rel.GetPublishedAt().Time
+	info.TagName = rel.TagName
+	info.Name = rel.Name
+	info.Body = rel.Body
+	info.BodyHTML = markdown.Render(rel.Body)
+	info.PublishedAt = rel.PublishedAt
 	info.IsLatest = isLatest
-	for _, a := range rel.Assets {
+	info.Channel = ChannelStable
+	if rel.Prerelease {
+		info.Channel = ChannelBeta
+	}
+	for _, a := range assets {
 		var downloadURL string
 		if downloadUrlBase != "" {
 			// 如果提供了 downloadUrlBase，则直接使用它。
@@ -80,77 +185,164 @@ func (d *Downloader) DownloadLatest(ctx context.Context, launcher string, destBa
 				baseURL = "http://" + baseURL
 			}
 			baseURL = strings.TrimRight(baseURL, "/")
-			downloadURL = fmt.Sprintf("%s/download/%s/%s/%s", baseURL, launcher, version, a.GetName())
+			downloadURL = fmt.Sprintf("%s/download/%s/%s/%s", baseURL, launcher, version, a.Name)
 		} else if serverAddress != "" {
-			downloadURL = FormatDownloadURL(serverAddress, serverPort, "", launcher, version, a.GetName())
+			downloadURL = FormatDownloadURL(serverAddress, serverPort, "", launcher, version, a.Name)
 		} else {
 			publicIP, err := getPublicIP()
 			if err != nil {
-				log.Printf("无法获取公网 IP: %v。回退到资源 %s 的 GitHub URL", err, a.GetName())
-				downloadURL = a.GetBrowserDownloadURL()
+				log.Printf("无法获取公网 IP: %v。回退到资源 %s 的上游 URL", err, a.Name)
+				downloadURL = a.DownloadURL
 			} else {
-				downloadURL = FormatDownloadURL("", serverPort, publicIP, launcher, version, a.GetName())
+				downloadURL = FormatDownloadURL("", serverPort, publicIP, launcher, version, a.Name)
 			}
 		}
 		info.Assets = append(info.Assets, ReleaseAssetSimple{
-			Name: a.GetName(),
+			Name: a.Name,
 			URL:  downloadURL,
-			Size: a.GetSize(),
+			Size: a.Size,
+			Kind: a.Kind,
 		})
 	}
 
-	indexPath := filepath.Join(dir, "index.json")
-	b, err := json.MarshalIndent(info, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("序列化 index.json 失败: %w", err)
+	// 源码压缩包等资源在下载前无法得知大小，下载完成后以实际文件大小回填
+	for i, a := range info.Assets {
+		if a.Size != 0 {
+			continue
+		}
+		if fi, err := os.Stat(filepath.Join(dir, a.Name)); err == nil {
+			info.Assets[i].Size = int(fi.Size())
+		}
 	}
-	if err := os.WriteFile(indexPath, b, 0o644); err != nil {
-		return "", fmt.Errorf("写入 index.json 失败: %w", err)
+	for i, a := range info.Assets {
+		if sum, ok := digests[a.Name]; ok && sum.SHA256 != "" {
+			info.Assets[i].SHA256 = sum.SHA256
+			info.Assets[i].SHA1 = sum.SHA1
+		}
 	}
-	log.Printf("已将版本信息写入 %s", indexPath)
-
-	client := d.httpClient
-	if proxyURL != "" {
-		proxy, err := url.Parse(proxyURL)
+	if err := verifyUpstreamChecksums(dir, info.Assets); err != nil {
+		// 校验失败时不发布该版本，删除不一致的文件，由下载队列重新下载
+		var mismatch *ChecksumMismatchError
+		if errors.As(err, &mismatch) {
+			log.Printf("警告: %s: %v，拒绝发布版本 %s", launcher, err, version)
+			os.Remove(filepath.Join(dir, mismatch.Asset))
+		}
+		return "", err
+	}
+	// 只用于校验的校验和文件不发布
+	published := info.Assets[:0]
+	for _, a := range info.Assets {
+		if a.Kind == kindVerifyOnly {
+			os.Remove(filepath.Join(dir, a.Name))
+			continue
+		}
+		published = append(published, a)
+	}
+	info.Assets = published
+	if err := writeChecksums(dir, info.Assets); err != nil {
+		return "", err
+	}
+	// 解析 APK 元数据，解析失败只记录日志；签名证书与清单分开读取，清单无法解析的 APK 也要参与签名校验
+	var apks []apkSigners
+	for i, a := range info.Assets {
+		if !strings.EqualFold(filepath.Ext(a.Name), ".apk") {
+			continue
+		}
+		apkPath := filepath.Join(dir, a.Name)
+		check := apkSigners{Asset: a.Name}
+		meta, err := apk.Parse(apkPath)
 		if err != nil {
-			return "", fmt.Errorf("解析代理URL失败: %w", err)
+			log.Printf("解析 APK %s 失败: %v", a.Name, err)
+			check.Err = fmt.Errorf("无法解析清单: %w", err)
+		} else {
+			check.Package = meta.Package
+		}
+		signers, err := apk.Signers(apkPath)
+		if err != nil && check.Err == nil {
+			check.Err = fmt.Errorf("无法读取签名: %w", err)
 		}
-		// 为代理创建新的客户端，因为默认客户端可能是共享的
-		client = &http.Client{
-			Timeout:   d.httpClient.Timeout,
-			Transport: &http.Transport{Proxy: http.ProxyURL(proxy)},
+		check.Signers = signers
+		if meta != nil {
+			meta.Signers = signers
+			info.Assets[i].Apk = meta
 		}
+		apks = append(apks, check)
 	}
-
-	var wg sync.WaitGroup
-	errCh := make(chan error, len(rel.Assets))
-
-	for _, asset := range rel.Assets {
-		wg.Add(1)
-		go func(asset *github.ReleaseAsset) {
-			defer wg.Done()
-			d.semaphore <- struct{}{}
-			defer func() { <-d.semaphore }()
-
-			err := d.downloadAsset(ctx, client, asset, dir, assetProxyURL, xgetEnabled, xgetDomain)
-			if err != nil {
-				errCh <- err
+	if err := verifySigners(launcher, version, apks); err != nil {
+		var mismatch *SignerMismatchError
+		if errors.As(err, &mismatch) {
+			log.Printf("警告: %s: %v", launcher, err)
+			if qdir, qerr := quarantine(dir, destBase, launcher, version); qerr != nil {
+				log.Printf("%s: %v", launcher, qerr)
+			} else {
+				log.Printf("警告: %s: 版本 %s 已隔离至 %s。确认新证书可信后，删除 apk_signers 表中的对应记录和隔离目录，下次扫描时会重新镜像", launcher, version, qdir)
 			}
-		}(asset)
+		}
+		return "", err
 	}
 
-	wg.Wait()
-	close(errCh)
+	// 全部资源下载并校验通过后才写入发布说明和 index.json
+	if rel.Body != "" {
+		notesPath := filepath.Join(dir, ReleaseNotesFile)
+		if err := os.WriteFile(notesPath, []byte(rel.Body), 0o644); err != nil {
+			return "", fmt.Errorf("写入发布说明失败: %w", err)
+		}
+	}
+	if err := writeIndex(filepath.Join(dir, "index.json"), &info); err != nil {
+		return "", err
+	}
 
-	for err := range errCh {
-		if err != nil {
+	if opts.BeforePublish != nil {
+		if err := opts.BeforePublish(); err != nil {
 			return "", err
 		}
 	}
-
+	finalDir := filepath.Join(destBase, launcher, version)
+	if err := publishDir(dir, finalDir); err != nil {
+		return "", err
+	}
+	indexPath := filepath.Join(finalDir, "index.json")
+	log.Printf("已发布版本 %s", finalDir)
 	return indexPath, nil
 }
 
+// publishDir 将暂存目录移动到发布位置。发布位置已存在（如旧版本残留的目录）时先移走再替换，失败时尽量恢复。
+func publishDir(staging, final string) error {
+	if err := os.MkdirAll(filepath.Dir(final), 0o755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	old := staging + ".old"
+	replaced := false
+	if _, err := os.Stat(final); err == nil {
+		os.RemoveAll(old)
+		if err := os.Rename(final, old); err != nil {
+			return fmt.Errorf("移走旧目录 %s 失败: %w", final, err)
+		}
+		replaced = true
+	}
+	if err := os.Rename(staging, final); err != nil {
+		if replaced {
+			os.Rename(old, final)
+		}
+		return fmt.Errorf("发布版本目录 %s 失败: %w", final, err)
+	}
+	if replaced {
+		os.RemoveAll(old)
+	}
+	return nil
+}
+
+func writeIndex(indexPath string, info *ReleaseInfo) error {
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 index.json 失败: %w", err)
+	}
+	if err := os.WriteFile(indexPath, b, 0o644); err != nil {
+		return fmt.Errorf("写入 index.json 失败: %w", err)
+	}
+	return nil
+}
+
 // 缓存公网 IP，避免重复请求
 var (
 	publicIP     string
@@ -201,88 +393,173 @@ func getPublicIP() (string, error) {
 	return result, nil
 }
 
-func (d *Downloader) downloadAsset(ctx context.Context, client *http.Client, asset *github.ReleaseAsset, dir, assetProxyURL string, xgetEnabled bool, xgetDomain string) error {
-	name := asset.GetName()
+// downloadAsset 下载单个资源并返回其摘要，资源没有下载链接时返回空摘要。
+// sl 为任务占用的下载槽位，每种加速方式都先将槽位转移到其改写后的主机再连接
+func (d *Downloader) downloadAsset(ctx context.Context, client *http.Client, asset source.Asset, dir string, opts Options, sl *slot) (Digests, error) {
+	name := asset.Name
 	outfile := filepath.Join(dir, name)
 
 	if fileInfo, err := os.Stat(outfile); err == nil {
-		if fileInfo.Size() == int64(asset.GetSize()) {
+		// 上游未提供大小时，已存在的文件即为完整下载（下载过程中写入的是 .partial 文件）
+		if fileInfo.Size() == int64(asset.Size) || (asset.Size == 0 && fileInfo.Size() > 0) {
 			log.Printf("文件 %s 已存在且大小一致，跳过下载。", name)
-			return nil
+			return hashFile(outfile)
 		}
-		log.Printf("文件 %s 已存在但大小不一致 (本地: %d, 远程: %d)，将重新下载。", name, fileInfo.Size(), asset.GetSize())
+		log.Printf("文件 %s 已存在但大小不一致 (本地: %d, 远程: %d)，将重新下载。", name, fileInfo.Size(), asset.Size)
 	}
 
-	downloadURL := asset.GetBrowserDownloadURL()
-	if downloadURL != "" && assetProxyURL != "" {
-		downloadURL = assetProxyURL + downloadURL
-	}
-	if downloadURL != "" && xgetEnabled && strings.HasPrefix(downloadURL, "https://github.com/") {
-		downloadURL = strings.Replace(downloadURL, "https://github.com/", xgetDomain+"/gh/", 1)
-	}
-	if downloadURL == "" {
+	if asset.DownloadURL == "" {
 		log.Printf("资源 %s 没有下载链接，跳过", name)
-		return nil
+		return Digests{}, nil
 	}
 	if name == "" {
-		name = filepath.Base(downloadURL)
+		name = filepath.Base(asset.DownloadURL)
 	}
-	log.Printf("开始下载 %s 到 %s", downloadURL, outfile)
 
+	// 依次尝试各加速方式（按健康度排序），全部失败时由下载队列退避后重试
 	partial := outfile + ".partial"
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
-	if err != nil {
-		return err
+	segmented := useSegments(asset, opts)
+	var lastErr error
+	for _, c := range downloadCandidates(asset.DownloadURL, opts.Accelerators) {
+		if err := sl.moveHost(ctx, assetHost(c.url)); err != nil {
+			return Digests{}, err
+		}
+		log.Printf("开始下载 %s 到 %s", c.url, outfile)
+		var sum Digests
+		var err error
+		start := time.Now()
+		if segmented {
+			sum, err = d.fetchSegmented(ctx, client, asset, c.url, partial, opts.Segments, sl.units)
+			if errors.Is(err, errSegmentsUnsupported) {
+				log.Printf("%s 的上游不支持分段下载，改用单连接下载", name)
+				segmented = false
+			}
+		}
+		if !segmented {
+			sum, err = d.fetchAsset(ctx, client, asset, c.url, partial)
+		}
+		if err == nil {
+			var size int64
+			if fi, statErr := os.Stat(partial); statErr == nil {
+				size = fi.Size()
+			}
+			recordAccelResult(c, nil, size, time.Since(start))
+			if err := os.Rename(partial, outfile); err != nil {
+				return Digests{}, err
+			}
+			os.Remove(metaPath(partial))
+			log.Printf("完成下载 %s", outfile)
+			return sum, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || errors.Is(err, errWindowClosed) {
+			return Digests{}, lastErr
+		}
+		recordAccelResult(c, err, 0, 0)
+		if c.accel != "" {
+			log.Printf("下载 %s 失败: %v，尝试下一种加速方式", c.url, err)
+		}
 	}
+	// 保留 .partial 文件，下次重试（包括进程重启后）从断点继续
+	return Digests{}, lastErr
+}
 
-	var resp *http.Response
-	for i := 0; i < 3; i++ {
-		resp, err = client.Do(req)
-		if err == nil && resp.StatusCode == http.StatusOK {
-			break
-		}
-		if resp != nil {
-			resp.Body.Close()
+// fetchAsset 将资源下载到 partial 文件。partial 文件已存在且记录了上游的 ETag/Last-Modified 时，
+// 使用 Range + If-Range 从断点继续下载；上游不支持 Range 或资源已变化时从头下载。
+func (d *Downloader) fetchAsset(ctx context.Context, client *http.Client, asset source.Asset, downloadURL, partial string) (Digests, error) {
+	var offset int64
+	meta := loadPartialMeta(partial)
+	if fi, err := os.Stat(partial); err == nil {
+		// 各加速方式下载的是同一文件，断点按上游原始地址记录；不同 CDN 的校验值不一致时 If-Range 会返回完整文件
+		if meta != nil && meta.URL == asset.DownloadURL && meta.validator() != "" {
+			offset = fi.Size()
+		} else {
+			discardPartial(partial)
 		}
-		log.Printf("下载 %s 失败，5秒后重试...", downloadURL)
-		time.Sleep(5 * time.Second)
 	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return Digests{}, err
+	}
+	for k, v := range asset.Header {
+		req.Header[k] = v
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", meta.validator())
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return Digests{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("下载资源 %s 失败，状态码: %d", downloadURL, resp.StatusCode)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			discardPartial(partial)
+			return Digests{}, fmt.Errorf("上游返回的 Content-Range %q 与断点 %d 不一致", resp.Header.Get("Content-Range"), offset)
+		}
+		flags |= os.O_APPEND
+		log.Printf("从 %d 字节处继续下载 %s", offset, asset.Name)
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			log.Printf("上游不支持断点续传或资源已变化，重新下载 %s", asset.Name)
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// 断点超出了上游文件大小，本地数据已无效
+		discardPartial(partial)
+		return Digests{}, fmt.Errorf("下载资源 %s 失败，断点 %d 超出文件大小", downloadURL, offset)
+	default:
+		return Digests{}, fmt.Errorf("下载资源 %s 失败，状态码: %d", downloadURL, resp.StatusCode)
+	}
+	if offset == 0 {
+		// 记录上游的校验信息，供之后续传时使用
+		m := &partialMeta{URL: asset.DownloadURL, ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+		if err := savePartialMeta(partial, m); err != nil {
+			return Digests{}, err
+		}
 	}
 
-	f, err := os.Create(partial)
+	digest := newDigester()
+	if offset > 0 {
+		if err := hashExisting(partial, digest); err != nil {
+			return Digests{}, err
+		}
+	}
+	f, err := os.OpenFile(partial, flags, 0o644)
 	if err != nil {
-		return err
+		return Digests{}, err
 	}
-	defer func() {
-		f.Close()
-		os.Remove(partial)
-	}()
+	defer f.Close()
 
+	total := resp.ContentLength
+	if total >= 0 {
+		total += offset
+	}
 	progressWriter := &progressWriter{
-		total:      resp.ContentLength,
-		fileName:   name,
+		total:      total,
+		written:    offset,
+		fileName:   asset.Name,
 		lastUpdate: time.Now(),
 	}
-	if _, err := io.Copy(f, io.TeeReader(resp.Body, progressWriter)); err != nil {
-		return err
+	if _, err := io.Copy(io.MultiWriter(f, digest), io.TeeReader(d.limitReader(ctx, asset, resp.Body), progressWriter)); err != nil {
+		return Digests{}, err
 	}
-
 	if err := f.Close(); err != nil {
-		return err
-	}
-	if err := os.Rename(partial, outfile); err != nil {
-		return err
+		return Digests{}, err
 	}
 
-	log.Printf("完成下载 %s", outfile)
-	return nil
+	if asset.Size > 0 && progressWriter.written != int64(asset.Size) {
+		discardPartial(partial)
+		return Digests{}, fmt.Errorf("资源 %s 大小不一致 (下载: %d, 上游: %d)", asset.Name, progressWriter.written, asset.Size)
+	}
+	return digest.Sum(), nil
 }
 
 type progressWriter struct {
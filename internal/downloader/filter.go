@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AssetFilter 根据文件名决定是否镜像某个资源。
+// 规则默认为 glob 通配符（如 *.apk），以 "regex:" 开头时视为正则表达式，与 repo_selector 的约定一致。
+// 配置了 include 时，文件名必须匹配其中至少一条；匹配任意 exclude 规则的文件总是被排除。
+type AssetFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+func NewAssetFilter(include, exclude []string) (*AssetFilter, error) {
+	f := &AssetFilter{}
+	for _, p := range include {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, m)
+	}
+	for _, p := range exclude {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, m)
+	}
+	return f, nil
+}
+
+// Match 判断文件名是否应被镜像，nil 过滤器匹配所有文件
+func (f *AssetFilter) Match(name string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+func matchAny(matchers []func(string) bool, name string) bool {
+	for _, m := range matchers {
+		if m(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func compilePattern(p string) (func(string) bool, error) {
+	if strings.HasPrefix(p, "regex:") {
+		re, err := regexp.Compile(strings.TrimPrefix(p, "regex:"))
+		if err != nil {
+			return nil, fmt.Errorf("资源过滤规则中的正则表达式无效 %q: %w", p, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(p, ""); err != nil {
+		return nil, fmt.Errorf("资源过滤规则中的通配符无效 %q: %w", p, err)
+	}
+	return func(name string) bool {
+		ok, _ := path.Match(p, name)
+		return ok
+	}, nil
+}
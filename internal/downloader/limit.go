@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"lemwood_mirror/internal/source"
+)
+
+// rateLimiter 是所有下载共享的令牌桶，限制从上游读取数据的总速率
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 字节/秒
+	tokens float64
+	last   time.Time
+}
+
+// rateChunk 是每次读取的最大字节数，使限速更平滑
+const rateChunk = 32 << 10
+
+func newRateLimiter(bytesPerSec int64) *rateLimiter {
+	return &rateLimiter{rate: float64(bytesPerSec), tokens: float64(bytesPerSec), last: time.Now()}
+}
+
+// wait 预留 n 字节的额度，额度不足时等待到额度补足或 ctx 结束
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	// 最多积累 1 秒的额度
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// errWindowClosed 表示下载窗口已结束，下载中止并保留已下载的部分，等下一个窗口从断点继续
+var errWindowClosed = errors.New("下载窗口已结束")
+
+type limitedReader struct {
+	ctx  context.Context
+	r    io.Reader
+	l    *rateLimiter         // 为 nil 时不限速
+	open func(time.Time) bool // 不为 nil 时，返回 false 表示下载窗口已结束
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.open != nil && !r.open(time.Now()) {
+		return 0, errWindowClosed
+	}
+	if len(p) > rateChunk {
+		p = p[:rateChunk]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 && r.l != nil {
+		if werr := r.l.wait(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// SetRateLimit 设置从上游下载的总速率上限（字节/秒），0 表示不限制。需在开始下载前调用
+func (d *Downloader) SetRateLimit(bytesPerSec int64) {
+	if bytesPerSec <= 0 {
+		d.limiter = nil
+		return
+	}
+	d.limiter = newRateLimiter(bytesPerSec)
+}
+
+// limitReader 为上游响应加上总速率限制；受下载窗口限制的资源在窗口结束时中止读取
+func (d *Downloader) limitReader(ctx context.Context, asset source.Asset, r io.Reader) io.Reader {
+	var open func(time.Time) bool
+	if !d.exemptFromWindows(asset) {
+		open = func(now time.Time) bool { return d.allowedNow(asset, now) }
+	}
+	if d.limiter == nil && open == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, r: r, l: d.limiter, open: open}
+}
+
+// Window 是一天中的时间段（本地时间），End 早于 Start 时表示跨越午夜
+type Window struct {
+	Start, End time.Duration // 距当天 0 点的时间
+}
+
+// ParseWindows 解析 "HH:MM-HH:MM" 格式的时间段
+func ParseWindows(list []string) ([]Window, error) {
+	var windows []Window
+	for _, s := range list {
+		start, end, ok := strings.Cut(s, "-")
+		if !ok {
+			return nil, fmt.Errorf("无效的下载窗口 %q，格式应为 HH:MM-HH:MM", s)
+		}
+		var w Window
+		var err error
+		if w.Start, err = parseClock(start); err != nil {
+			return nil, fmt.Errorf("无效的下载窗口 %q: %w", s, err)
+		}
+		if w.End, err = parseClock(end); err != nil {
+			return nil, fmt.Errorf("无效的下载窗口 %q: %w", s, err)
+		}
+		if w.Start == w.End {
+			return nil, fmt.Errorf("无效的下载窗口 %q，开始和结束时间相同", s)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains 判断 t 是否处于时间段内
+func (w Window) Contains(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start < w.End {
+		return clock >= w.Start && clock < w.End
+	}
+	return clock >= w.Start || clock < w.End
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", int(w.Start.Hours()), int(w.Start.Minutes())%60, int(w.End.Hours()), int(w.End.Minutes())%60)
+}
+
+// SetWindows 设置允许下载大文件的时间段，大小达到 minSize（字节）的资源只在这些时间段内下载，
+// 窗口结束时未完成的下载会中止，下一个窗口开始后从断点继续；
+// minSize 为 0 时所有资源都受限制。windows 为空表示不限制。需在开始下载前调用
+func (d *Downloader) SetWindows(windows []Window, minSize int64) {
+	d.windows = windows
+	d.windowMinSize = minSize
+}
+
+// exemptFromWindows 判断资源是否不受下载窗口限制。上游未提供大小的资源不受限制
+func (d *Downloader) exemptFromWindows(asset source.Asset) bool {
+	return len(d.windows) == 0 || asset.Size == 0 || int64(asset.Size) < d.windowMinSize
+}
+
+// allowedNow 判断资源当前是否可以下载
+func (d *Downloader) allowedNow(asset source.Asset, now time.Time) bool {
+	if d.exemptFromWindows(asset) {
+		return true
+	}
+	for _, w := range d.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lemwood_mirror/internal/db"
+)
+
+func TestQueuePausesDownloadWhenWindowCloses(t *testing.T) {
+	initTestDB(t)
+	var resumedFrom atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if rng := r.Header.Get("Range"); rng != "" {
+			resumedFrom.Store(rng)
+			w.Header().Set("Content-Range", "bytes 3-4/5")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("lo"))
+			return
+		}
+		w.Header().Set("Content-Length", "5")
+		w.Write([]byte("he"))
+		w.(http.Flusher).Flush()
+		// 下载窗口在传输过程中结束
+		time.Sleep(2100 * time.Millisecond)
+		w.Write([]byte("l"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	base := t.TempDir()
+
+	now := time.Now()
+	clock := time.Duration(now.Hour())*time.Hour + time.Duration(now.Minute())*time.Minute + time.Duration(now.Second())*time.Second
+	q := newTestQueue(t, base)
+	q.d.SetWindows([]Window{{Start: (clock + 23*time.Hour) % (24 * time.Hour), End: (clock + 2*time.Second) % (24 * time.Hour)}}, 0)
+	if err := q.Submit("fcl", testRelease("v1", srv.URL), true); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.Run(ctx)
+	}()
+	job := waitJob(t, func(j db.DownloadJob) bool { return j.State == db.JobPending && j.LastError != "" })
+	cancel()
+	<-done
+	if job.Attempts != 0 {
+		t.Errorf("window close counted as a failed attempt: %+v", job)
+	}
+	partial := filepath.Join(stagingPath(base, "fcl", "v1"), "app.bin.partial")
+	if fi, err := os.Stat(partial); err != nil || fi.Size() == 0 || fi.Size() >= 5 {
+		t.Fatalf("partial download not kept: %v %v", fi, err)
+	}
+
+	// 下一个下载窗口内从断点继续
+	q = newTestQueue(t, base)
+	q.run(t)
+	p := q.waitPublished(t)
+	if rng, _ := resumedFrom.Load().(string); rng != "bytes=3-" {
+		t.Errorf("resume request Range = %q", rng)
+	}
+	if b, _ := os.ReadFile(filepath.Join(filepath.Dir(p.indexPath), "app.bin")); string(b) != "hello" {
+		t.Errorf("resumed file = %q", b)
+	}
+}
@@ -0,0 +1,454 @@
+package downloader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"lemwood_mirror/internal/db"
+	"lemwood_mirror/internal/source"
+)
+
+// 下载任务的重试策略：第 n 次失败后等待 jobBackoffBase * 2^(n-1)，最长 jobBackoffMax，
+// 失败 jobMaxAttempts 次后任务进入 failed 状态，需通过管理接口手动重试
+const (
+	jobMaxAttempts  = 8
+	jobBackoffBase  = 30 * time.Second
+	jobBackoffMax   = time.Hour
+	jobPollInterval = 30 * time.Second
+)
+
+// QueueHooks 是队列向调用方获取下载选项、通知发布结果的回调。
+// release 与任务持久化在数据库中，进程重启后恢复的 release 同样通过这些回调获取当前配置下的选项。
+type QueueHooks struct {
+	// Options 返回启动器当前的下载选项，启动器已从配置中删除时返回错误
+	Options func(launcher string) (Options, error)
+	// BeforePublish 在版本目录移动到发布位置之前调用（如清除旧版本的 latest 标记），返回错误时放弃发布
+	BeforePublish func(launcher, version string, isLatest bool) error
+	// Published 在版本发布后调用，indexPath 为发布后的 index.json 路径
+	Published func(launcher, version, indexPath string, isLatest bool)
+}
+
+// Queue 是持久化在 SQLite 中的下载队列，每个资源对应一个任务。
+// 扫描发现新版本后通过 Submit 登记 release 并创建任务，由 Run 在后台下载；
+// 版本的全部任务完成后校验并发布该版本。release 信息与任务都保存在数据库中，进程重启后无需重新扫描即可继续下载和发布。
+type Queue struct {
+	d        *Downloader
+	destBase string
+	hooks    QueueHooks
+
+	mu         sync.Mutex
+	publishing map[string]bool // launcher/version -> 正在发布
+	wake       chan struct{}
+	workers    sync.WaitGroup // 队列启动的下载与发布 goroutine
+
+	// 各启动器最近一次分配到槽位的轮次，仅由 Run 所在的 goroutine 访问
+	turns    map[string]int
+	nextTurn int
+}
+
+func NewQueue(d *Downloader, destBase string, hooks QueueHooks) *Queue {
+	return &Queue{
+		d:          d,
+		destBase:   destBase,
+		hooks:      hooks,
+		publishing: make(map[string]bool),
+		wake:       make(chan struct{}, 1),
+		turns:      make(map[string]int),
+	}
+}
+
+func releaseKey(launcher, version string) string {
+	return launcher + "/" + version
+}
+
+// jobAsset 从任务记录还原待下载的资源
+func jobAsset(job db.DownloadJob, opts Options) source.Asset {
+	a := source.Asset{Name: job.Asset, DownloadURL: job.URL, Size: job.Size, Kind: job.Kind}
+	if opts.AssetHeader != nil {
+		a.Header = opts.AssetHeader(job.URL)
+	}
+	return a
+}
+
+// Submit 登记 release 并为其资源创建下载任务。重复提交同一版本时保留已有任务的状态和重试记录。
+// 提交新的 latest 版本时，同一启动器尚未发布的旧 latest 版本会降级为普通历史版本；
+// 以非 latest 身份重复提交（如历史版本同步）不会取消已有的 latest 标记。
+func (q *Queue) Submit(launcher string, rel *source.Release, isLatest bool) error {
+	if rel == nil {
+		return errors.New("release 为空")
+	}
+	version := rel.Version()
+	if !source.ValidFileName(version) {
+		// 版本名来自上游的 tag 或名称，直接用作目录名，不能借路径分隔符或 .. 逃出启动器目录
+		return fmt.Errorf("版本名 %q 不是有效的目录名", version)
+	}
+	q.mu.Lock()
+	publishing := q.publishing[releaseKey(launcher, version)]
+	q.mu.Unlock()
+	if publishing {
+		return nil
+	}
+	opts, err := q.hooks.Options(launcher)
+	if err != nil {
+		return err
+	}
+
+	if isLatest {
+		demoted, err := db.DemoteLatestReleases(launcher, version)
+		if err != nil {
+			return fmt.Errorf("更新 latest 标记失败: %w", err)
+		}
+		for _, v := range demoted {
+			log.Printf("%s: 版本 %s 尚未完成下载，已有更新的版本 %s，不再将其标记为 latest", launcher, v, version)
+		}
+	}
+	err = db.SaveRelease(&db.QueuedRelease{
+		Launcher:    launcher,
+		Version:     version,
+		ReleaseID:   rel.ID,
+		TagName:     rel.TagName,
+		Name:        rel.Name,
+		Body:        rel.Body,
+		PublishedAt: rel.PublishedAt,
+		Prerelease:  rel.Prerelease,
+		IsLatest:    isLatest,
+	})
+	if err != nil {
+		return fmt.Errorf("登记 release 失败: %w", err)
+	}
+
+	deferred := 0
+	for _, a := range selectAssets(rel, opts) {
+		job := db.DownloadJob{Launcher: launcher, Version: version, Asset: a.Name, URL: a.DownloadURL, Size: a.Size, Kind: a.Kind}
+		if err := db.EnqueueJob(job); err != nil {
+			return fmt.Errorf("创建下载任务失败: %w", err)
+		}
+		if !q.d.allowedNow(a, time.Now()) {
+			deferred++
+		}
+	}
+	if deferred > 0 {
+		log.Printf("%s: 版本 %s 的 %d 个大文件将在下载窗口 %v 内下载", launcher, version, deferred, q.d.windows)
+	}
+
+	// 任务可能在上次运行时已全部完成（如发布前进程退出），没有资源的版本也无需下载，此时直接尝试发布
+	q.workers.Add(1)
+	go func() {
+		defer q.workers.Done()
+		q.tryPublish(launcher, version)
+	}()
+	q.Wake()
+	return nil
+}
+
+// Wake 通知队列立即检查待执行的任务
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Retry 立即重试任务，id 为 0 时重试全部失败的任务，返回重试的任务数
+func (q *Queue) Retry(id int64) (int64, error) {
+	n, err := db.RetryJob(id)
+	if err == nil && n > 0 {
+		q.Wake()
+	}
+	return n, err
+}
+
+// Run 在后台执行到期的下载任务，直到 ctx 结束
+func (q *Queue) Run(ctx context.Context) {
+	if err := db.ResetRunningJobs(); err != nil {
+		log.Printf("恢复下载任务状态失败: %v", err)
+	}
+	if n, err := db.DeleteOrphanJobs(); err != nil {
+		log.Printf("清理下载任务失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已取消 %d 个缺少 release 信息的下载任务，对应版本会在下次扫描时重新登记", n)
+	}
+	// 上次退出前已全部下载完成但尚未发布的版本
+	if releases, err := db.ListReleases(); err != nil {
+		log.Printf("读取等待发布的版本失败: %v", err)
+	} else {
+		for _, r := range releases {
+			q.workers.Add(1)
+			go func() {
+				defer q.workers.Done()
+				q.tryPublish(r.Launcher, r.Version)
+			}()
+		}
+	}
+	for {
+		q.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			// 等待进行中的任务随 ctx 取消退出
+			q.workers.Wait()
+			return
+		case <-q.wake:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// dispatch 在空闲的下载槽位上启动到期的任务。
+// 每个槽位分配给正在下载的任务最少的启动器（相同时轮流分配），资源很多的 release 不会占满全部槽位。
+func (q *Queue) dispatch(ctx context.Context) {
+	jobs, err := db.DueJobs()
+	if err != nil {
+		log.Printf("读取下载任务失败: %v", err)
+		return
+	}
+	type dueJob struct {
+		job   db.DownloadJob
+		asset source.Asset
+		opts  Options
+	}
+	var order []string
+	due := make(map[string][]dueJob)
+	options := make(map[string]Options)
+	now := time.Now()
+	for _, job := range jobs {
+		if !source.ValidFileName(job.Version) || !source.ValidFileName(job.Asset) {
+			// 加入校验之前登记的任务
+			q.abandon(job, fmt.Errorf("版本名 %q 或资源名 %q 不是有效的文件名", job.Version, job.Asset))
+			continue
+		}
+		opts, ok := options[job.Launcher]
+		if !ok {
+			var err error
+			if opts, err = q.hooks.Options(job.Launcher); err != nil {
+				// 启动器已删除或配置无效，修正配置后可通过管理接口重试
+				q.abandon(job, err)
+				continue
+			}
+			options[job.Launcher] = opts
+		}
+		asset := jobAsset(job, opts)
+		if !q.d.allowedNow(asset, now) {
+			continue // 大文件等待下载窗口
+		}
+		if _, ok := due[job.Launcher]; !ok {
+			order = append(order, job.Launcher)
+		}
+		due[job.Launcher] = append(due[job.Launcher], dueJob{job, asset, opts})
+	}
+
+	for !q.d.sched.full() {
+		launcher := ""
+		for _, l := range order {
+			if len(due[l]) == 0 {
+				continue
+			}
+			if launcher == "" {
+				launcher = l
+				continue
+			}
+			n, best := q.d.sched.launcherRunning(l), q.d.sched.launcherRunning(launcher)
+			if n < best || (n == best && q.turns[l] < q.turns[launcher]) {
+				launcher = l
+			}
+		}
+		if launcher == "" {
+			return
+		}
+		started := false
+		for i, dj := range due[launcher] {
+			// 按首选加速方式改写后的主机计数；分段下载的任务每段占用一个槽位，槽位不足时减少同时打开的连接
+			host := assetHost(dj.asset.DownloadURL)
+			if c := downloadCandidates(dj.asset.DownloadURL, dj.opts.Accelerators); len(c) > 0 {
+				host = assetHost(c[0].url)
+			}
+			want := 1
+			if useSegments(dj.asset, dj.opts) {
+				want = dj.opts.Segments
+			}
+			sl := q.d.sched.tryAcquire(launcher, host, want)
+			if sl == nil {
+				continue // 该主机没有空闲槽位，尝试该启动器的其他任务
+			}
+			due[launcher] = append(due[launcher][:i:i], due[launcher][i+1:]...)
+			q.nextTurn++
+			q.turns[launcher] = q.nextTurn
+			q.start(ctx, dj.job, dj.asset, dj.opts, sl)
+			started = true
+			break
+		}
+		if !started {
+			due[launcher] = nil
+		}
+	}
+}
+
+// start 在已占用的槽位上执行任务
+func (q *Queue) start(ctx context.Context, job db.DownloadJob, asset source.Asset, opts Options, sl *slot) {
+	if err := db.StartJob(job.ID); err != nil {
+		sl.release()
+		log.Printf("更新下载任务 %d 失败: %v", job.ID, err)
+		return
+	}
+	q.workers.Add(1)
+	go func() {
+		defer func() {
+			sl.release()
+			q.Wake()
+			q.workers.Done()
+		}()
+		q.runJob(ctx, job, asset, opts, sl)
+	}()
+}
+
+func (q *Queue) runJob(ctx context.Context, job db.DownloadJob, asset source.Asset, opts Options, sl *slot) {
+	dir := stagingPath(q.destBase, job.Launcher, job.Version)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		q.fail(job, fmt.Errorf("创建目录 %s 失败: %w", dir, err))
+		return
+	}
+	client, err := q.d.client(opts)
+	if err != nil {
+		q.fail(job, err)
+		return
+	}
+	sum, err := q.d.downloadAsset(ctx, client, asset, dir, opts, sl)
+	if errors.Is(err, errWindowClosed) {
+		// 保留已下载的部分，dispatch 会在下一个下载窗口内重新开始该任务
+		log.Printf("%s: 下载窗口已结束，暂停下载 %s/%s", job.Launcher, job.Version, job.Asset)
+		if dbErr := db.PauseJob(job.ID, err.Error()); dbErr != nil {
+			log.Printf("更新下载任务 %d 失败: %v", job.ID, dbErr)
+		}
+		return
+	}
+	if err != nil {
+		q.fail(job, err)
+		return
+	}
+	if err := db.FinishJob(job.ID, sum.SHA256, sum.SHA1); err != nil {
+		log.Printf("更新下载任务 %d 失败: %v", job.ID, err)
+		return
+	}
+	q.tryPublish(job.Launcher, job.Version)
+}
+
+// abandon 将无法执行的任务直接标记为失败，不再自动重试
+func (q *Queue) abandon(job db.DownloadJob, err error) {
+	log.Printf("%s: 无法下载 %s/%s: %v", job.Launcher, job.Version, job.Asset, err)
+	if dbErr := db.FailJob(job.ID, db.JobFailed, 0, err.Error()); dbErr != nil {
+		log.Printf("更新下载任务 %d 失败: %v", job.ID, dbErr)
+	}
+}
+
+// fail 记录任务失败，未超过重试次数时按指数退避安排重试
+func (q *Queue) fail(job db.DownloadJob, err error) {
+	attempts := job.Attempts + 1
+	state, delay := db.JobPending, backoff(attempts)
+	if attempts >= jobMaxAttempts {
+		state, delay = db.JobFailed, 0
+		log.Printf("%s: 下载 %s/%s 失败 %d 次，放弃重试: %v", job.Launcher, job.Version, job.Asset, attempts, err)
+	} else {
+		log.Printf("%s: 下载 %s/%s 失败（第 %d 次）: %v，%s 后重试", job.Launcher, job.Version, job.Asset, attempts, err, delay)
+	}
+	if dbErr := db.FailJob(job.ID, state, delay, err.Error()); dbErr != nil {
+		log.Printf("更新下载任务 %d 失败: %v", job.ID, dbErr)
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	d := jobBackoffBase
+	for i := 1; i < attempts && d < jobBackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, jobBackoffMax)
+}
+
+// tryPublish 在版本的全部任务完成后发布该版本
+func (q *Queue) tryPublish(launcher, version string) {
+	key := releaseKey(launcher, version)
+	q.mu.Lock()
+	if q.publishing[key] {
+		q.mu.Unlock()
+		return
+	}
+	jobs, err := db.VersionJobs(launcher, version)
+	if err != nil {
+		q.mu.Unlock()
+		log.Printf("%s: 读取版本 %s 的下载任务失败: %v", launcher, version, err)
+		return
+	}
+	digests := make(map[string]Digests)
+	byAsset := make(map[string]db.DownloadJob)
+	var assets []source.Asset
+	for _, job := range jobs {
+		if job.State != db.JobDone {
+			q.mu.Unlock()
+			return
+		}
+		digests[job.Asset] = Digests{SHA256: job.SHA256, SHA1: job.SHA1}
+		byAsset[job.Asset] = job
+		assets = append(assets, jobAsset(job, Options{}))
+	}
+	queued, err := db.GetRelease(launcher, version)
+	if err != nil {
+		q.mu.Unlock()
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("%s: 读取版本 %s 失败: %v", launcher, version, err)
+		}
+		return
+	}
+	q.publishing[key] = true
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.publishing, key)
+		q.mu.Unlock()
+	}()
+
+	opts, err := q.hooks.Options(launcher)
+	if err != nil {
+		log.Printf("%s: 无法发布版本 %s: %v", launcher, version, err)
+		return
+	}
+	isLatest := queued.IsLatest
+	if q.hooks.BeforePublish != nil {
+		opts.BeforePublish = func() error { return q.hooks.BeforePublish(launcher, version, isLatest) }
+	}
+	rel := &source.Release{
+		ID:          queued.ReleaseID,
+		TagName:     queued.TagName,
+		Name:        queued.Name,
+		Body:        queued.Body,
+		PublishedAt: queued.PublishedAt,
+		Prerelease:  queued.Prerelease,
+	}
+	indexPath, err := publishRelease(launcher, q.destBase, rel, assets, opts, isLatest, digests)
+
+	var checksum *ChecksumMismatchError
+	var signer *SignerMismatchError
+	switch {
+	case err == nil, errors.As(err, &signer):
+		// 已发布或已隔离，不再需要这些任务
+		if dbErr := db.DeleteVersionJobs(launcher, version); dbErr != nil {
+			log.Printf("%s: 删除版本 %s 的下载任务失败: %v", launcher, version, dbErr)
+		}
+	case errors.As(err, &checksum):
+		// 不一致的文件已被删除，重新下载该资源
+		if job, ok := byAsset[checksum.Asset]; ok {
+			q.fail(job, err)
+			q.Wake()
+		}
+	}
+	if err != nil {
+		log.Printf("%s: 发布版本 %s 失败: %v", launcher, version, err)
+		return
+	}
+	if q.hooks.Published != nil {
+		q.hooks.Published(launcher, version, indexPath, isLatest)
+	}
+}
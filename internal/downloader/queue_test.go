@@ -0,0 +1,311 @@
+package downloader
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lemwood_mirror/internal/db"
+	"lemwood_mirror/internal/source"
+)
+
+type publishedRelease struct {
+	launcher, version, indexPath string
+	isLatest                     bool
+}
+
+type testQueue struct {
+	*Queue
+	published chan publishedRelease
+	removed   atomic.Bool // 为 true 时模拟启动器已从配置中删除
+}
+
+func newTestQueue(t *testing.T, base string) *testQueue {
+	t.Helper()
+	tq := &testQueue{published: make(chan publishedRelease, 8)}
+	tq.Queue = NewQueue(NewDownloader(1, 2, 0), base, QueueHooks{
+		Options: func(launcher string) (Options, error) {
+			if tq.removed.Load() {
+				return Options{}, errors.New("启动器 " + launcher + " 已从配置中删除")
+			}
+			return Options{DownloadUrlBase: "https://mirror.example.com"}, nil
+		},
+		Published: func(launcher, version, indexPath string, isLatest bool) {
+			tq.published <- publishedRelease{launcher, version, indexPath, isLatest}
+		},
+	})
+	// 在关闭数据库之前等待 Submit 启动的发布 goroutine 退出
+	t.Cleanup(tq.workers.Wait)
+	return tq
+}
+
+// run 启动队列，测试结束时（关闭数据库之前）停止队列并等待进行中的任务退出
+func (tq *testQueue) run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	go func() {
+		defer close(done)
+		tq.Run(ctx)
+	}()
+}
+
+func (tq *testQueue) waitPublished(t *testing.T) publishedRelease {
+	t.Helper()
+	select {
+	case p := <-tq.published:
+		return p
+	case <-time.After(10 * time.Second):
+		t.Fatal("release was not published")
+		return publishedRelease{}
+	}
+}
+
+// waitJob 等待唯一的下载任务满足条件
+func waitJob(t *testing.T, cond func(db.DownloadJob) bool) db.DownloadJob {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		jobs, err := db.ListJobs("", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) == 1 && cond(jobs[0]) {
+			return jobs[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not reach the expected state: %+v", jobs)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// assetServer 在 failing 为 true 时对所有请求返回 500
+func assetServer(t *testing.T, failing *atomic.Bool, requests *atomic.Int32) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testRelease 返回测试用的 release，baseURL 为空时不含资源
+func testRelease(tag, baseURL string) *source.Release {
+	rel := &source.Release{
+		TagName:     tag,
+		Body:        "notes for " + tag,
+		PublishedAt: time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+	}
+	if baseURL != "" {
+		rel.Assets = []source.Asset{{Name: "app.bin", DownloadURL: baseURL + "/app.bin", Size: 5}}
+	}
+	return rel
+}
+
+func readIndex(t *testing.T, path string) ReleaseInfo {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info ReleaseInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestQueuePublishesReleaseWithoutAssets(t *testing.T) {
+	initTestDB(t)
+	q := newTestQueue(t, t.TempDir())
+	if err := q.Submit("fcl", testRelease("v1", ""), true); err != nil {
+		t.Fatal(err)
+	}
+	p := q.waitPublished(t)
+	if p.version != "v1" || !p.isLatest {
+		t.Errorf("published %+v", p)
+	}
+	if info := readIndex(t, p.indexPath); info.Body != "notes for v1" || info.BodyHTML != "<p>notes for v1</p>\n" || len(info.Assets) != 0 {
+		t.Errorf("index %+v", info)
+	}
+	if _, err := db.GetRelease("fcl", "v1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("release record not removed after publish: %v", err)
+	}
+}
+
+func TestQueueBackoffAndRetry(t *testing.T) {
+	initTestDB(t)
+	var failing atomic.Bool
+	var requests atomic.Int32
+	failing.Store(true)
+	srv := assetServer(t, &failing, &requests)
+	q := newTestQueue(t, t.TempDir())
+	if err := q.Submit("fcl", testRelease("v1", srv.URL), true); err != nil {
+		t.Fatal(err)
+	}
+	q.run(t)
+
+	job := waitJob(t, func(j db.DownloadJob) bool { return j.Attempts == 1 && j.State == db.JobPending && j.LastError != "" })
+	if wait := time.Until(job.NextRunAt); wait < 20*time.Second || wait > 40*time.Second {
+		t.Errorf("next run in %v, want about %v", wait, jobBackoffBase)
+	}
+
+	failing.Store(false)
+	if n, err := q.Retry(job.ID); err != nil || n != 1 {
+		t.Fatalf("Retry = %d, %v", n, err)
+	}
+	p := q.waitPublished(t)
+	info := readIndex(t, p.indexPath)
+	if len(info.Assets) != 1 || info.Assets[0].SHA256 == "" || !info.PublishedAt.Equal(time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("index %+v", info)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestQueueGivesUpAfterMaxAttempts(t *testing.T) {
+	initTestDB(t)
+	var failing atomic.Bool
+	var requests atomic.Int32
+	failing.Store(true)
+	srv := assetServer(t, &failing, &requests)
+	q := newTestQueue(t, t.TempDir())
+	if err := q.Submit("fcl", testRelease("v1", srv.URL), true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.DB.Exec("UPDATE download_jobs SET attempts = ?", jobMaxAttempts-1); err != nil {
+		t.Fatal(err)
+	}
+	q.run(t)
+
+	waitJob(t, func(j db.DownloadJob) bool { return j.State == db.JobFailed && j.Attempts == jobMaxAttempts })
+	failing.Store(false)
+	if n, err := q.Retry(0); err != nil || n != 1 {
+		t.Fatalf("Retry(0) = %d, %v", n, err)
+	}
+	q.waitPublished(t)
+}
+
+func TestQueueResumesAfterRestart(t *testing.T) {
+	initTestDB(t)
+	var failing atomic.Bool
+	var requests atomic.Int32
+	srv := assetServer(t, &failing, &requests)
+	base := t.TempDir()
+
+	// 第一个进程登记版本后在下载中途退出
+	if err := newTestQueue(t, base).Submit("fcl", testRelease("v2", srv.URL), true); err != nil {
+		t.Fatal(err)
+	}
+	jobs, _ := db.ListJobs("", 10)
+	if len(jobs) != 1 {
+		t.Fatalf("jobs %+v", jobs)
+	}
+	if err := db.StartJob(jobs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后不需要重新扫描即可继续下载并以 latest 身份发布
+	q := newTestQueue(t, base)
+	q.run(t)
+	p := q.waitPublished(t)
+	if p.version != "v2" || !p.isLatest {
+		t.Errorf("published %+v", p)
+	}
+	if info := readIndex(t, p.indexPath); info.Body != "notes for v2" || !info.IsLatest || len(info.Assets) != 1 {
+		t.Errorf("index %+v", info)
+	}
+}
+
+func TestQueueLatestDemotion(t *testing.T) {
+	initTestDB(t)
+	q := newTestQueue(t, t.TempDir())
+	for _, step := range []struct {
+		version string
+		latest  bool
+	}{{"v1", true}, {"v2", true}, {"v2", false}} {
+		if err := q.Submit("fcl", testRelease(step.version, "http://127.0.0.1:1"), step.latest); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for version, want := range map[string]bool{"v1": false, "v2": true} {
+		r, err := db.GetRelease("fcl", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.IsLatest != want {
+			t.Errorf("%s is_latest = %v, want %v", version, r.IsLatest, want)
+		}
+	}
+}
+
+func TestQueueAbandonsJobsOfRemovedLauncher(t *testing.T) {
+	initTestDB(t)
+	var failing atomic.Bool
+	var requests atomic.Int32
+	srv := assetServer(t, &failing, &requests)
+	q := newTestQueue(t, t.TempDir())
+	if err := q.Submit("fcl", testRelease("v1", srv.URL), true); err != nil {
+		t.Fatal(err)
+	}
+	q.removed.Store(true)
+	q.run(t)
+
+	job := waitJob(t, func(j db.DownloadJob) bool { return j.State == db.JobFailed })
+	if !strings.Contains(job.LastError, "已从配置中删除") || requests.Load() != 0 {
+		t.Errorf("job %+v, %d requests", job, requests.Load())
+	}
+}
+
+func TestQueueDropsOrphanJobs(t *testing.T) {
+	initTestDB(t)
+	if err := db.EnqueueJob(db.DownloadJob{Launcher: "fcl", Version: "v0", Asset: "app.bin", URL: "http://127.0.0.1:1/app.bin"}); err != nil {
+		t.Fatal(err)
+	}
+	q := newTestQueue(t, t.TempDir())
+	q.run(t)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		jobs, _ := db.ListJobs("", 10)
+		if len(jobs) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("orphan job not removed: %+v", jobs)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestQueueRejectsUnsafeVersion(t *testing.T) {
+	initTestDB(t)
+	q := newTestQueue(t, t.TempDir())
+	for _, tag := range []string{"../x", "a/b", `a\b`, ".."} {
+		if err := q.Submit("fcl", testRelease(tag, "http://127.0.0.1:1"), true); err == nil {
+			t.Errorf("Submit(%q) accepted an unsafe version", tag)
+		}
+	}
+	if jobs, _ := db.ListJobs("", 10); len(jobs) != 0 {
+		t.Errorf("jobs created for unsafe versions: %+v", jobs)
+	}
+	if releases, _ := db.ListReleases(); len(releases) != 0 {
+		t.Errorf("releases recorded for unsafe versions: %+v", releases)
+	}
+}
@@ -0,0 +1,82 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partialMeta 记录 .partial 文件对应的上游资源，断点续传时用于通过 If-Range 确认上游没有变化
+type partialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func metaPath(partial string) string {
+	return partial + ".meta"
+}
+
+func loadPartialMeta(partial string) *partialMeta {
+	b, err := os.ReadFile(metaPath(partial))
+	if err != nil {
+		return nil
+	}
+	var m partialMeta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return &m
+}
+
+func savePartialMeta(partial string, m *partialMeta) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath(partial), b, 0o644)
+}
+
+// discardPartial 删除无法续传的 .partial 文件及其元数据
+func discardPartial(partial string) {
+	os.Remove(partial)
+	os.Remove(metaPath(partial))
+}
+
+// validator 返回用于 If-Range 的校验值。If-Range 只接受强 ETag，弱 ETag 时改用 Last-Modified
+func (m *partialMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// contentRangeStart 解析 "bytes 100-999/1000" 格式的 Content-Range，返回起始字节，无法解析时返回 -1
+func contentRangeStart(v string) int64 {
+	v, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return -1
+	}
+	start, _, ok := strings.Cut(v, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// hashExisting 将已下载部分写入摘要计算器，续传完成后即可得到完整文件的摘要
+func hashExisting(path string, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,152 @@
+package downloader
+
+import (
+	"context"
+	"net/url"
+	"sync"
+)
+
+// scheduler 是所有启动器共用的下载槽位，每个槽位对应一个上游连接（分段下载的任务占用多个槽位）。
+// 它限制全局和每个上游主机同时打开的连接数，并记录各启动器占用的槽位数，供队列公平调度
+type scheduler struct {
+	mu        sync.Mutex
+	limit     int
+	perHost   int // 0 表示不限制
+	running   int
+	hosts     map[string]int
+	launchers map[string]int
+	// changed 在槽位被归还时关闭并替换，用于等待主机槽位
+	changed chan struct{}
+}
+
+// slot 是一个任务占用的槽位，units 为可同时打开的连接数，host 为当前计入的上游主机
+type slot struct {
+	s        *scheduler
+	launcher string
+	host     string
+	hostHeld bool // 等待新主机槽位期间为 false，此时不计入任何主机
+	units    int
+}
+
+func newScheduler(limit, perHost int) *scheduler {
+	return &scheduler{
+		limit:     limit,
+		perHost:   perHost,
+		hosts:     make(map[string]int),
+		launchers: make(map[string]int),
+		changed:   make(chan struct{}),
+	}
+}
+
+// tryAcquire 在全局和主机均有空闲槽位时为任务占用槽位，最多占用 want 个（不超过剩余的空闲槽位）。
+// 没有空闲槽位时返回 nil
+func (s *scheduler) tryAcquire(launcher, host string, want int) *slot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(max(want, 1), s.limit-s.running)
+	if s.perHost > 0 {
+		n = min(n, s.perHost-s.hosts[host])
+	}
+	if n < 1 {
+		return nil
+	}
+	s.running += n
+	s.hosts[host] += n
+	s.launchers[launcher] += n
+	return &slot{s: s, launcher: launcher, host: host, hostHeld: true, units: n}
+}
+
+// addLocked 调整槽位计数，调用方需持有 s.mu
+func (s *scheduler) addLocked(m map[string]int, key string, n int) {
+	if m[key] += n; m[key] <= 0 {
+		delete(m, key)
+	}
+}
+
+// notifyLocked 唤醒等待主机槽位的任务，调用方需持有 s.mu
+func (s *scheduler) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (sl *slot) release() {
+	s := sl.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sl.units == 0 {
+		return
+	}
+	s.running -= sl.units
+	if sl.hostHeld {
+		s.addLocked(s.hosts, sl.host, -sl.units)
+	}
+	s.addLocked(s.launchers, sl.launcher, -sl.units)
+	sl.units, sl.hostHeld = 0, false
+	s.notifyLocked()
+}
+
+// moveHost 将槽位转移到实际连接的主机（经加速方式改写后的地址）。
+// 目标主机没有空闲槽位时先归还原主机的槽位再等待，避免任务之间互相等待；
+// 目标主机的空闲槽位少于已占用的连接数时只转移可用的部分，多余的全局槽位随之归还。
+func (sl *slot) moveHost(ctx context.Context, host string) error {
+	s := sl.s
+	s.mu.Lock()
+	if sl.hostHeld && host == sl.host {
+		s.mu.Unlock()
+		return nil
+	}
+	if sl.hostHeld {
+		s.addLocked(s.hosts, sl.host, -sl.units)
+		sl.hostHeld = false
+		s.notifyLocked()
+	}
+	for {
+		free := sl.units
+		if s.perHost > 0 {
+			free = min(free, s.perHost-s.hosts[host])
+		}
+		if free >= 1 {
+			extra := sl.units - free
+			s.running -= extra
+			s.addLocked(s.launchers, sl.launcher, -extra)
+			s.hosts[host] += free
+			sl.host, sl.hostHeld, sl.units = host, true, free
+			if extra > 0 {
+				s.notifyLocked()
+			}
+			s.mu.Unlock()
+			return nil
+		}
+		wait := s.changed
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wait:
+		}
+		s.mu.Lock()
+	}
+}
+
+// full 判断全局槽位是否已满
+func (s *scheduler) full() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running >= s.limit
+}
+
+// launcherRunning 返回启动器占用的槽位数
+func (s *scheduler) launcherRunning(launcher string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.launchers[launcher]
+}
+
+// assetHost 返回下载地址的主机名，用于按主机限制并发
+func assetHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,139 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"lemwood_mirror/internal/source"
+)
+
+func TestSchedulerAcquireUnits(t *testing.T) {
+	s := newScheduler(5, 3)
+	a := s.tryAcquire("fcl", "github.com", 4)
+	if a == nil || a.units != 3 {
+		t.Fatalf("segmented job got %+v, want 3 units capped by host", a)
+	}
+	if sl := s.tryAcquire("zl", "github.com", 1); sl != nil {
+		t.Fatal("host cap exceeded")
+	}
+	b := s.tryAcquire("zl", "gitee.com", 4)
+	if b == nil || b.units != 2 {
+		t.Fatalf("second job got %+v, want the 2 remaining global units", b)
+	}
+	if !s.full() || s.launcherRunning("fcl") != 3 || s.launcherRunning("zl") != 2 {
+		t.Errorf("running %d, launchers %v", s.running, s.launchers)
+	}
+	if sl := s.tryAcquire("hmcl", "example.com", 1); sl != nil {
+		t.Fatal("global limit exceeded")
+	}
+
+	a.release()
+	a.release() // 重复归还不影响计数
+	b.release()
+	if s.running != 0 || len(s.hosts) != 0 || len(s.launchers) != 0 {
+		t.Errorf("leaked slots: running %d, hosts %v, launchers %v", s.running, s.hosts, s.launchers)
+	}
+}
+
+func TestSlotMoveHostClampsUnits(t *testing.T) {
+	s := newScheduler(6, 2)
+	busy := s.tryAcquire("zl", "ghproxy.example", 1)
+	sl := s.tryAcquire("fcl", "github.com", 2)
+	if err := sl.moveHost(context.Background(), "ghproxy.example"); err != nil {
+		t.Fatal(err)
+	}
+	if sl.units != 1 || s.hosts["ghproxy.example"] != 2 || s.hosts["github.com"] != 0 || s.running != 2 {
+		t.Errorf("units %d, hosts %v, running %d", sl.units, s.hosts, s.running)
+	}
+	sl.release()
+	busy.release()
+	if s.running != 0 || len(s.hosts) != 0 || len(s.launchers) != 0 {
+		t.Errorf("leaked slots: running %d, hosts %v, launchers %v", s.running, s.hosts, s.launchers)
+	}
+}
+
+func TestSlotMoveHostWaits(t *testing.T) {
+	s := newScheduler(4, 1)
+	busy := s.tryAcquire("zl", "ghproxy.example", 1)
+	sl := s.tryAcquire("fcl", "github.com", 1)
+
+	done := make(chan error, 1)
+	go func() { done <- sl.moveHost(context.Background(), "ghproxy.example") }()
+	select {
+	case err := <-done:
+		t.Fatalf("moveHost returned %v while the host was full", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// 等待期间不再占用原主机
+	if other := s.tryAcquire("hmcl", "github.com", 1); other == nil {
+		t.Error("waiting job still holds its previous host")
+	} else {
+		other.release()
+	}
+
+	busy.release()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("moveHost did not resume after the host was released")
+	}
+	if s.hosts["ghproxy.example"] != 1 {
+		t.Errorf("hosts %v", s.hosts)
+	}
+	sl.release()
+}
+
+func TestSlotMoveHostCanceled(t *testing.T) {
+	s := newScheduler(4, 1)
+	busy := s.tryAcquire("zl", "ghproxy.example", 1)
+	sl := s.tryAcquire("fcl", "github.com", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sl.moveHost(ctx, "ghproxy.example"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	sl.release()
+	busy.release()
+	if s.running != 0 || len(s.hosts) != 0 || len(s.launchers) != 0 {
+		t.Errorf("leaked slots: running %d, hosts %v, launchers %v", s.running, s.hosts, s.launchers)
+	}
+}
+
+func TestFetchSegmentedLimitsConnections(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	var active, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "app.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	asset := source.Asset{Name: "app.bin", DownloadURL: srv.URL + "/app.bin", Size: len(data)}
+	partial := filepath.Join(t.TempDir(), "app.bin.partial")
+	d := NewDownloader(1, 4, 0)
+	if _, err := d.fetchSegmented(context.Background(), srv.Client(), asset, asset.DownloadURL, partial, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(partial); !bytes.Equal(got, data) {
+		t.Error("merged file differs from upstream")
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("%d concurrent connections, want at most 2", p)
+	}
+}
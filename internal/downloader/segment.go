@@ -0,0 +1,262 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"lemwood_mirror/internal/source"
+)
+
+// DefaultSegmentThreshold 是未配置阈值时启用分段下载的最小文件大小
+const DefaultSegmentThreshold = 64 << 20
+
+// errSegmentsUnsupported 表示上游不支持分段下载，应回退到单连接下载
+var errSegmentsUnsupported = errors.New("上游不支持分段下载")
+
+// segmentMeta 记录分段下载的上游信息，进程重启后据此判断已下载的分段能否继续使用
+type segmentMeta struct {
+	URL       string `json:"url"`
+	Validator string `json:"validator"`
+	Size      int64  `json:"size"`
+	Segments  int    `json:"segments"`
+}
+
+func segmentMetaPath(partial string) string {
+	return partial + ".segments"
+}
+
+func segmentPath(partial string, i int) string {
+	return fmt.Sprintf("%s.seg%d", partial, i)
+}
+
+// discardSegments 删除分段文件及其元数据
+func discardSegments(partial string, n int) {
+	for i := 0; i < n; i++ {
+		os.Remove(segmentPath(partial, i))
+	}
+	os.Remove(segmentMetaPath(partial))
+}
+
+// useSegments 判断资源是否应分段下载：需配置了多个分段，且上游提供的大小达到阈值
+func useSegments(asset source.Asset, opts Options) bool {
+	threshold := opts.SegmentThreshold
+	if threshold <= 0 {
+		threshold = DefaultSegmentThreshold
+	}
+	return opts.Segments > 1 && asset.Size > 0 && int64(asset.Size) >= threshold
+}
+
+// probeRanges 通过 HEAD 请求确认上游支持 Range，并返回用于 If-Range 的校验值。
+// 没有可用的校验值时无法保证各分段来自同一文件，视为不支持。
+func probeRanges(ctx context.Context, client *http.Client, asset source.Asset, downloadURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, downloadURL, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range asset.Header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength != int64(asset.Size) {
+		return "", errSegmentsUnsupported
+	}
+	m := partialMeta{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if m.validator() == "" {
+		return "", errSegmentsUnsupported
+	}
+	return m.validator(), nil
+}
+
+// fetchSegmented 将资源按字节范围分成 opts.Segments 段并行下载，各段写入独立的分段文件，
+// 全部完成后按顺序合并到 partial 文件并计算摘要。每段都携带 If-Range，上游文件中途变化时放弃已下载的分段。
+// 同时最多打开 conns 个连接（任务占用的下载槽位数），分段划分不随 conns 变化，重试时仍可续传。
+func (d *Downloader) fetchSegmented(ctx context.Context, client *http.Client, asset source.Asset, downloadURL, partial string, segments, conns int) (Digests, error) {
+	validator, err := probeRanges(ctx, client, asset, downloadURL)
+	if err != nil {
+		return Digests{}, err
+	}
+	size := int64(asset.Size)
+	meta := segmentMeta{URL: asset.DownloadURL, Validator: validator, Size: size, Segments: segments}
+	if b, err := os.ReadFile(segmentMetaPath(partial)); err == nil {
+		var old segmentMeta
+		if json.Unmarshal(b, &old) != nil || old != meta {
+			log.Printf("上游资源 %s 已变化，丢弃已下载的分段", asset.Name)
+			discardSegments(partial, max(old.Segments, segments))
+		}
+	}
+	b, _ := json.Marshal(meta)
+	if err := os.WriteFile(segmentMetaPath(partial), b, 0o644); err != nil {
+		return Digests{}, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var downloaded atomic.Int64
+	sem := make(chan struct{}, max(conns, 1))
+	segSize := (size + int64(segments) - 1) / int64(segments)
+	for i := 0; i < segments; i++ {
+		start := int64(i) * segSize
+		end := min(start+segSize, size) - 1
+		if start > end {
+			break
+		}
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+			if err := d.fetchSegment(ctx, client, asset, downloadURL, validator, segmentPath(partial, i), start, end, &downloaded); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, start, end)
+	}
+
+	// 定期输出整体进度
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				n := downloaded.Load()
+				log.Printf("分段下载 %s: %d / %d (%.2f%%)", asset.Name, n, size, float64(n)/float64(size)*100)
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+	if firstErr != nil {
+		return Digests{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return Digests{}, err // 等待连接的分段未下载
+	}
+
+	return mergeSegments(partial, segments, size)
+}
+
+// fetchSegment 下载 [start, end] 范围到分段文件，分段文件已有的数据视为已完成部分
+func (d *Downloader) fetchSegment(ctx context.Context, client *http.Client, asset source.Asset, downloadURL, validator, path string, start, end int64, downloaded *atomic.Int64) error {
+	var have int64
+	if fi, err := os.Stat(path); err == nil {
+		have = fi.Size()
+	}
+	if have > end-start+1 {
+		os.Remove(path)
+		have = 0
+	}
+	downloaded.Add(have)
+	if have == end-start+1 {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range asset.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start+have, end))
+	req.Header.Set("If-Range", validator)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		// If-Range 不匹配时上游返回 200 和完整文件，说明资源已变化
+		return fmt.Errorf("下载 %s 的分段失败，状态码: %d", asset.Name, resp.StatusCode)
+	}
+	if got := contentRangeStart(resp.Header.Get("Content-Range")); got != start+have {
+		return fmt.Errorf("上游返回的 Content-Range %q 与分段起点 %d 不一致", resp.Header.Get("Content-Range"), start+have)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := io.Copy(f, io.LimitReader(&countingReader{r: d.limitReader(ctx, asset, resp.Body), n: downloaded}, end-start+1-have))
+	if err != nil {
+		return err
+	}
+	if have+n != end-start+1 {
+		return fmt.Errorf("%s 的分段数据不完整 (%d / %d)", asset.Name, have+n, end-start+1)
+	}
+	return f.Close()
+}
+
+// mergeSegments 按顺序合并分段文件并计算摘要，合并结果的大小必须与上游一致
+func mergeSegments(partial string, segments int, size int64) (Digests, error) {
+	f, err := os.Create(partial)
+	if err != nil {
+		return Digests{}, err
+	}
+	defer f.Close()
+	digest := newDigester()
+	w := io.MultiWriter(f, digest)
+	var total int64
+	for i := 0; i < segments; i++ {
+		seg, err := os.Open(segmentPath(partial, i))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Digests{}, err
+		}
+		n, err := io.Copy(w, seg)
+		seg.Close()
+		if err != nil {
+			return Digests{}, err
+		}
+		total += n
+	}
+	if err := f.Close(); err != nil {
+		return Digests{}, err
+	}
+	if total != size {
+		discardSegments(partial, segments)
+		os.Remove(partial)
+		return Digests{}, fmt.Errorf("分段合并后大小不一致 (合并: %d, 上游: %d)", total, size)
+	}
+	discardSegments(partial, segments)
+	return digest.Sum(), nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,145 @@
+package downloader
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/db"
+)
+
+// QuarantineDir 是存放被隔离版本的目录名，位于存储根目录下，不会被索引或对外提供下载
+const QuarantineDir = ".quarantine"
+
+// SignerMismatchError 表示 APK 的签名证书与此前记录的不一致，
+// 或启动器已记录签名证书而该 APK 的包名或签名无法读取、无法校验
+type SignerMismatchError struct {
+	Asset   string
+	Package string
+	Known   string
+	Got     string
+	Reason  string // 无法校验的原因
+}
+
+func (e *SignerMismatchError) Error() string {
+	if e.Known == "" {
+		return fmt.Sprintf("%s 的签名证书无法校验: %s", e.Asset, e.Reason)
+	}
+	got := e.Got
+	if got == "" {
+		got = "未签名"
+		if e.Reason != "" {
+			got = e.Reason
+		}
+	}
+	return fmt.Sprintf("%s (%s) 的签名证书发生变化: 已知 %s, 当前 %s", e.Asset, e.Package, e.Known, got)
+}
+
+// apkSigners 是参与签名校验的单个 APK，包名或签名无法读取时对应字段为空，Err 记录原因
+type apkSigners struct {
+	Asset   string
+	Package string
+	Signers []string
+	Err     error
+}
+
+func (a apkSigners) reason() string {
+	switch {
+	case a.Err != nil:
+		return a.Err.Error()
+	case a.Package == "":
+		return "无法确定包名"
+	default:
+		return "未签名"
+	}
+}
+
+// verifySigners 将版本中各 APK 的签名证书与数据库中已知的证书比对。
+// 包名首次出现时记录其证书（首次信任），证书不一致时返回 *SignerMismatchError。
+// 启动器已记录过签名证书时，包名或签名无法读取的 APK 同样视为不一致，避免借解析失败绕过校验。
+func verifySigners(launcher, version string, apks []apkSigners) error {
+	pinned, err := db.HasAPKSigners(launcher)
+	if err != nil {
+		return fmt.Errorf("查询签名证书失败: %w", err)
+	}
+
+	// 先全部比对，全部通过后再记录新包名，避免隔离的版本留下记录
+	pending := make(map[string]string)
+	for _, a := range apks {
+		got := strings.Join(a.Signers, ",")
+		if a.Package == "" {
+			if pinned {
+				return &SignerMismatchError{Asset: a.Asset, Reason: a.reason()}
+			}
+			log.Printf("%s: 无法校验 APK %s 的签名证书: %s", launcher, a.Asset, a.reason())
+			continue
+		}
+		known, err := db.GetAPKSigners(launcher, a.Package)
+		if errors.Is(err, sql.ErrNoRows) {
+			if got == "" {
+				if pinned {
+					return &SignerMismatchError{Asset: a.Asset, Package: a.Package, Reason: a.reason()}
+				}
+				log.Printf("%s: APK %s 未签名或签名无法解析，不记录签名证书", launcher, a.Asset)
+				continue
+			}
+			if prev, ok := pending[a.Package]; ok && prev != got {
+				return &SignerMismatchError{Asset: a.Asset, Package: a.Package, Known: prev, Got: got}
+			}
+			pending[a.Package] = got
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("查询签名证书失败: %w", err)
+		}
+		if known != got {
+			e := &SignerMismatchError{Asset: a.Asset, Package: a.Package, Known: known, Got: got}
+			if got == "" {
+				e.Reason = a.reason()
+			}
+			return e
+		}
+	}
+	for pkg, signers := range pending {
+		if err := db.SaveAPKSigners(launcher, pkg, signers, version); err != nil {
+			return fmt.Errorf("记录签名证书失败: %w", err)
+		}
+		log.Printf("%s: 记录 %s 的签名证书 %s", launcher, pkg, signers)
+	}
+	return nil
+}
+
+// quarantine 将暂存的版本目录移动到 destBase/.quarantine/<launcher>/<version>-<时间戳>，返回隔离后的目录
+func quarantine(src, destBase, launcher, version string) (string, error) {
+	dir := filepath.Join(destBase, QuarantineDir, launcher)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("创建隔离目录失败: %w", err)
+	}
+	target := filepath.Join(dir, version+"-"+time.Now().Format("20060102-150405"))
+	if err := os.Rename(src, target); err != nil {
+		return "", fmt.Errorf("隔离版本 %s 失败: %w", version, err)
+	}
+	return target, nil
+}
+
+// IsQuarantined 判断版本是否已被隔离，已隔离的版本不会被重新下载，删除隔离目录后会在下次扫描时重试
+func IsQuarantined(destBase, launcher, version string) bool {
+	entries, err := os.ReadDir(filepath.Join(destBase, QuarantineDir, launcher))
+	if err != nil {
+		return false
+	}
+	// 隔离目录名为 <版本>-<时间戳>，需完整匹配时间戳，避免 1.2.0 匹配到 1.2.0-beta-... 的隔离目录
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(version) + `-\d{8}-\d{6}$`)
+	for _, e := range entries {
+		if e.IsDir() && re.MatchString(e.Name()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemwood_mirror/internal/db"
+	"lemwood_mirror/internal/source"
+)
+
+func initTestDB(t *testing.T) {
+	t.Helper()
+	if err := db.InitDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+}
+
+func TestVerifySigners(t *testing.T) {
+	initTestDB(t)
+	unreadable := errors.New("签名数据被截断")
+	steps := []struct {
+		name     string
+		apks     []apkSigners
+		mismatch bool
+	}{
+		{"unparsable before any pin", []apkSigners{{Asset: "bad.apk", Err: unreadable}}, false},
+		{"unsigned before any pin", []apkSigners{{Asset: "a.apk", Package: "com.a"}}, false},
+		{"conflicting signers in one release", []apkSigners{
+			{Asset: "a.apk", Package: "com.a", Signers: []string{"aa"}},
+			{Asset: "a2.apk", Package: "com.a", Signers: []string{"bb"}},
+		}, true},
+		{"first trust", []apkSigners{{Asset: "a.apk", Package: "com.a", Signers: []string{"aa"}}}, false},
+		{"same signer", []apkSigners{{Asset: "a.apk", Package: "com.a", Signers: []string{"aa"}}}, false},
+		{"changed signer", []apkSigners{{Asset: "a.apk", Package: "com.a", Signers: []string{"bb"}}}, true},
+		{"pinned package unsigned", []apkSigners{{Asset: "a.apk", Package: "com.a"}}, true},
+		{"pinned package unreadable", []apkSigners{{Asset: "a.apk", Package: "com.a", Err: unreadable}}, true},
+		{"unknown package after pin", []apkSigners{{Asset: "bad.apk", Err: unreadable, Signers: []string{"aa"}}}, true},
+		{"unsigned new package after pin", []apkSigners{{Asset: "b.apk", Package: "com.b"}}, true},
+		{"signed new package after pin", []apkSigners{{Asset: "b.apk", Package: "com.b", Signers: []string{"cc"}}}, false},
+	}
+	for _, s := range steps {
+		err := verifySigners("fcl", "v1", s.apks)
+		var mismatch *SignerMismatchError
+		if got := errors.As(err, &mismatch); got != s.mismatch || (!got && err != nil) {
+			t.Errorf("%s: err = %v, want mismatch %v", s.name, err, s.mismatch)
+		}
+	}
+	if known, _ := db.GetAPKSigners("fcl", "com.a"); known != "aa" {
+		t.Errorf("com.a signers = %q, want first trusted %q", known, "aa")
+	}
+	if known, _ := db.GetAPKSigners("fcl", "com.b"); known != "cc" {
+		t.Errorf("com.b signers = %q, want %q", known, "cc")
+	}
+}
+
+func TestPublishQuarantinesUnverifiableAPK(t *testing.T) {
+	initTestDB(t)
+	if err := db.SaveAPKSigners("fcl", "com.fcl", "aa", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	base := t.TempDir()
+	rel := &source.Release{TagName: "v2", Assets: []source.Asset{{Name: "fcl.apk", DownloadURL: "https://example.com/fcl.apk"}}}
+	staging := stagingPath(base, "fcl", "v2")
+	os.MkdirAll(staging, 0o755)
+	// 清单与签名都无法解析的 APK 不能借解析失败绕过签名校验
+	os.WriteFile(filepath.Join(staging, "fcl.apk"), []byte("not a zip"), 0o644)
+
+	_, err := publishRelease("fcl", base, rel, rel.Assets, Options{DownloadUrlBase: "https://mirror.example.com"}, true, nil)
+	var mismatch *SignerMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("err = %v, want *SignerMismatchError", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "fcl", "v2")); !os.IsNotExist(err) {
+		t.Error("unverifiable release was published")
+	}
+	if !IsQuarantined(base, "fcl", "v2") {
+		t.Error("unverifiable release was not quarantined")
+	}
+}
+
+func TestIsQuarantined(t *testing.T) {
+	base := t.TempDir()
+	for _, name := range []string{"1.2.0-beta-20260101-120000", "2.0-20260101-120000", "3.0-old"} {
+		os.MkdirAll(filepath.Join(base, QuarantineDir, "fcl", name), 0o755)
+	}
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"1.2.0", false},
+		{"1.2.0-beta", true},
+		{"2.0", true},
+		{"2", false},
+		{"3.0", false},
+		{"1.2.0-beta-2026", false},
+	}
+	for _, tt := range tests {
+		if got := IsQuarantined(base, "fcl", tt.version); got != tt.want {
+			t.Errorf("IsQuarantined(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+	if IsQuarantined(base, "other", "2.0") {
+		t.Error("quarantine leaked across launchers")
+	}
+}
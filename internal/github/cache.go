@@ -0,0 +1,93 @@
+package gh
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"lemwood_mirror/internal/db"
+)
+
+// cachingTransport 为 GitHub API 的 GET 请求附带上次响应的 ETag / Last-Modified。
+// 上游返回 304 时（不消耗速率限制配额），使用 SQLite 中缓存的响应体构造 200 响应，
+// 调用方因此拿到与上次相同的 release，并按“版本未变化”处理。
+// 它位于凭据池之下，缓存按凭据区分：不同凭据（包括匿名访问）可见的内容不同，不能互相复用缓存的响应。
+type cachingTransport struct {
+	base http.RoundTripper
+}
+
+// credentialKey 是请求上下文中凭据标识的键，由凭据池设置
+type credentialKey struct{}
+
+// cacheKey 返回请求的缓存键：凭据标识加 URL，匿名请求使用 anonymous
+func cacheKey(req *http.Request) string {
+	id, _ := req.Context().Value(credentialKey{}).(string)
+	if id == "" {
+		id = "anonymous"
+	}
+	return id + " " + req.URL.String()
+}
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || db.DB == nil {
+		return t.base.RoundTrip(req)
+	}
+	key := cacheKey(req)
+	cached, _ := db.GetHTTPCache(key)
+	if cached != nil {
+		// RoundTripper 不应修改原请求
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		header := http.Header{}
+		if err := json.Unmarshal([]byte(cached.Header), &header); err != nil {
+			header = http.Header{}
+		}
+		// 速率限制信息以本次响应为准
+		for k, v := range resp.Header {
+			if strings.HasPrefix(k, "X-Ratelimit-") {
+				header[k] = v
+			}
+		}
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header = header
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+		return resp, nil
+	}
+
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	header, _ := json.Marshal(resp.Header)
+	entry := &db.HTTPCacheEntry{ETag: etag, LastModified: lastModified, Header: string(header), Body: body}
+	if err := db.SaveHTTPCache(key, entry); err != nil {
+		log.Printf("缓存 GitHub 响应失败: %v", err)
+	}
+	return resp, nil
+}
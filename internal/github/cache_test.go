@@ -0,0 +1,63 @@
+package gh
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"lemwood_mirror/internal/db"
+)
+
+func TestCacheIsPerCredential(t *testing.T) {
+	if err := db.InitDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+
+	var revalidated atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 每个凭据看到的内容不同（例如只有令牌 a 能看到私有仓库）
+		etag := `"` + r.Header.Get("Authorization") + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			revalidated.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("request with %q revalidated another credential's ETag %s", r.Header.Get("Authorization"), r.Header.Get("If-None-Match"))
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte("body for " + r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	get := func(tokens ...string) string {
+		t.Helper()
+		p, err := newTokenPool(&cachingTransport{base: http.DefaultTransport}, tokens, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: p}).Get(srv.URL + "/repos/o/r/releases/latest")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	if got := get("a"); got != "body for Bearer a" {
+		t.Fatalf("first request: %q", got)
+	}
+	if got := get("b"); got != "body for Bearer b" {
+		t.Errorf("token b got %q", got)
+	}
+	if got := get(); got != "body for " {
+		t.Errorf("anonymous request got %q", got)
+	}
+	if got := get("a"); got != "body for Bearer a" || revalidated.Load() != 1 {
+		t.Errorf("cached response for token a: %q, %d revalidations", got, revalidated.Load())
+	}
+}
@@ -5,25 +5,30 @@ import (
     "errors"
     "net/http"
     "strings"
-    "time"
 
     github "github.com/google/go-github/v50/github"
-    "golang.org/x/oauth2"
 )
 
 type Client struct {
-	cli *github.Client
+	cli  *github.Client
+	pool *tokenPool
 }
 
-func NewClient(token string) *Client {
-	var httpClient *http.Client
-	if token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		)
-		httpClient = oauth2.NewClient(context.Background(), ts)
+// NewClient 创建 GitHub 客户端。tokens 与 app 组成凭据池，请求时自动选择剩余配额最多的凭据；
+// 两者都为空时以匿名身份访问。
+func NewClient(tokens []string, app *AppCredentials) (*Client, error) {
+	// 条件请求缓存位于凭据池之下，按实际使用的凭据区分缓存；304 响应不消耗速率限制配额
+	pool, err := newTokenPool(&cachingTransport{base: http.DefaultTransport}, tokens, app)
+	if err != nil {
+		return nil, err
 	}
-	return &Client{cli: github.NewClient(httpClient)}
+	httpClient := &http.Client{Transport: pool}
+	return &Client{cli: github.NewClient(httpClient), pool: pool}, nil
+}
+
+// TokenStates 返回凭据池中每个凭据的速率限制状态
+func (c *Client) TokenStates() []TokenState {
+	return c.pool.states()
 }
 
 // ParseOwnerRepo 从完整的 GitHub 仓库 URL 中提取所有者和仓库名。
@@ -43,15 +48,31 @@ func (c *Client) LatestRelease(ctx context.Context, owner, repo string) (*github
     return c.cli.Repositories.GetLatestRelease(ctx, owner, repo)
 }
 
-// BackoffIfRateLimited 检查响应是否受到速率限制，并在需要时休眠。
-func BackoffIfRateLimited(resp *github.Response) {
-    if resp == nil || resp.Rate.Remaining > 0 {
-        return
-    }
-	reset := resp.Rate.Reset.Time
-	// 休眠直到重置时间 + 小段余量
-	d := time.Until(reset) + 2*time.Second
-	if d > 0 && d < 15*time.Minute {
-		time.Sleep(d)
+// ListReleases 分页获取最近的 limit 个 release（跳过草稿），按发布时间从新到旧排列。
+// prerelease 为 false 时同时跳过预发布版本。
+func (c *Client) ListReleases(ctx context.Context, owner, repo string, limit int, prerelease bool) ([]*github.RepositoryRelease, *github.Response, error) {
+	var result []*github.RepositoryRelease
+	opts := &github.ListOptions{PerPage: 100}
+	if limit > 0 && limit < opts.PerPage {
+		opts.PerPage = limit
+	}
+	for {
+		releases, resp, err := c.cli.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, rel := range releases {
+			if rel.GetDraft() || (rel.GetPrerelease() && !prerelease) {
+				continue
+			}
+			result = append(result, rel)
+			if limit > 0 && len(result) >= limit {
+				return result, resp, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return result, resp, nil
+		}
+		opts.Page = resp.NextPage
 	}
 }
@@ -0,0 +1,313 @@
+package gh
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// AppCredentials 是 GitHub App 的安装凭据，用于换取 installation access token
+type AppCredentials struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKey     []byte // PEM 格式的 RSA 私钥
+}
+
+// TokenState 是单个凭据的速率限制状态，供管理接口展示
+type TokenState struct {
+	Label          string    `json:"label"`
+	Limit          int       `json:"limit"`
+	Remaining      int       `json:"remaining"`
+	Reset          time.Time `json:"reset"`
+	ExhaustedUntil time.Time `json:"exhausted_until,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+type credential struct {
+	label  string
+	id     string // 稳定的凭据标识（令牌的摘要或 App 安装），用于区分条件请求缓存
+	source oauth2.TokenSource
+	state  TokenState
+	known  bool // 是否已从响应中获得过速率限制信息
+}
+
+// tokenPool 在多个凭据之间轮换：每次请求选择剩余配额最多的凭据，
+// 遇到 403/429 速率限制时将其标记为耗尽直到重置时间，并使用下一个凭据重试。
+type tokenPool struct {
+	base  http.RoundTripper
+	mu    sync.Mutex
+	creds []*credential
+}
+
+func newTokenPool(base http.RoundTripper, tokens []string, app *AppCredentials) (*tokenPool, error) {
+	p := &tokenPool{base: base}
+	seen := make(map[string]bool)
+	for _, t := range tokens {
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		sum := sha256.Sum256([]byte(t))
+		p.creds = append(p.creds, &credential{
+			label:  "token " + maskToken(t),
+			id:     "token:" + hex.EncodeToString(sum[:8]),
+			source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: t}),
+		})
+	}
+	if app != nil && app.AppID != 0 {
+		key, err := parseRSAPrivateKey(app.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("解析 GitHub App 私钥失败: %w", err)
+		}
+		ats := &appTokenSource{app: app, key: key, base: base}
+		p.creds = append(p.creds, &credential{
+			label:  fmt.Sprintf("app %d/%d", app.AppID, app.InstallationID),
+			id:     fmt.Sprintf("app:%d/%d", app.AppID, app.InstallationID),
+			source: oauth2.ReuseTokenSource(nil, ats),
+		})
+	}
+	for _, c := range p.creds {
+		c.state.Label = c.label
+	}
+	return p, nil
+}
+
+func (p *tokenPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(p.creds) == 0 {
+		return p.base.RoundTrip(req)
+	}
+	tried := make(map[*credential]bool)
+	var lastResp *http.Response
+	for {
+		c := p.pick(tried)
+		if c == nil {
+			if lastResp != nil {
+				// 所有凭据都已耗尽，返回最后一次的响应，由调用方处理速率限制错误
+				return lastResp, nil
+			}
+			return nil, p.unavailableError()
+		}
+		tried[c] = true
+		tok, err := c.source.Token()
+		if err != nil {
+			p.recordError(c, err)
+			continue
+		}
+		r := req.Clone(context.WithValue(req.Context(), credentialKey{}, c.id))
+		tok.SetAuthHeader(r)
+		resp, err := p.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		if !p.update(c, resp) {
+			return resp, nil
+		}
+		if lastResp != nil {
+			lastResp.Body.Close()
+		}
+		lastResp = resp
+	}
+}
+
+// pick 选择未尝试过、未耗尽且剩余配额最多的凭据；尚无速率信息的凭据优先使用
+func (p *tokenPool) pick(tried map[*credential]bool) *credential {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best *credential
+	for _, c := range p.creds {
+		if tried[c] || now.Before(c.state.ExhaustedUntil) {
+			continue
+		}
+		if !c.known {
+			return c
+		}
+		if best == nil || c.state.Remaining > best.state.Remaining {
+			best = c
+		}
+	}
+	return best
+}
+
+// update 根据响应更新凭据的速率限制状态，返回该响应是否因速率限制失败（需要换凭据重试）
+func (p *tokenPool) update(c *credential, resp *http.Response) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := resp.Header
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Limit")); err == nil {
+		c.state.Limit = v
+		c.known = true
+	}
+	if v, err := strconv.Atoi(h.Get("X-RateLimit-Remaining")); err == nil {
+		c.state.Remaining = v
+	}
+	if v, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		c.state.Reset = time.Unix(v, 0)
+	}
+	c.state.LastError = ""
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	until := time.Time{}
+	if h.Get("X-RateLimit-Remaining") == "0" {
+		until = c.state.Reset
+	}
+	// 次级速率限制通过 Retry-After 告知等待时间
+	if secs, err := strconv.Atoi(h.Get("Retry-After")); err == nil {
+		until = time.Now().Add(time.Duration(secs) * time.Second)
+	}
+	if until.IsZero() {
+		if resp.StatusCode == http.StatusForbidden {
+			// 普通的权限错误，换凭据也无济于事
+			return false
+		}
+		until = time.Now().Add(time.Minute)
+	}
+	c.state.ExhaustedUntil = until
+	c.state.LastError = resp.Status
+	return true
+}
+
+func (p *tokenPool) recordError(c *credential, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.state.LastError = err.Error()
+	c.state.ExhaustedUntil = time.Now().Add(time.Minute)
+}
+
+// ErrNoCredential 表示凭据池中没有可用的凭据（均已耗尽或无法获取令牌）
+var ErrNoCredential = errors.New("没有可用的 GitHub 凭据")
+
+// unavailableError 返回包含最早恢复时间和最近一次错误的 ErrNoCredential
+func (p *tokenPool) unavailableError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var earliest time.Time
+	var lastErr string
+	for _, c := range p.creds {
+		if earliest.IsZero() || c.state.ExhaustedUntil.Before(earliest) {
+			earliest = c.state.ExhaustedUntil
+		}
+		if c.state.LastError != "" {
+			lastErr = c.state.LastError
+		}
+	}
+	if lastErr == "" {
+		lastErr = "未知错误"
+	}
+	return fmt.Errorf("%w，最早于 %s 恢复（最近一次错误: %s）", ErrNoCredential, earliest.Format(time.RFC3339), lastErr)
+}
+
+func (p *tokenPool) states() []TokenState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]TokenState, 0, len(p.creds))
+	for _, c := range p.creds {
+		list = append(list, c.state)
+	}
+	return list
+}
+
+// maskToken 仅保留令牌末尾 4 位
+func maskToken(t string) string {
+	if len(t) <= 4 {
+		return "****"
+	}
+	return "****" + t[len(t)-4:]
+}
+
+// appTokenSource 使用 App 私钥签发 JWT，并换取 installation access token。
+// 外层的 oauth2.ReuseTokenSource 会缓存令牌直到过期。
+type appTokenSource struct {
+	app  *AppCredentials
+	key  *rsa.PrivateKey
+	base http.RoundTripper
+}
+
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.signJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("https://api.github.com/app/installations/%d/access_tokens", s.app.InstallationID)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := (&http.Client{Transport: s.base, Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("获取 installation token 失败，状态码: %d", resp.StatusCode)
+	}
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	// 提前一分钟过期，避免请求途中令牌失效
+	return &oauth2.Token{AccessToken: body.Token, TokenType: "token", Expiry: body.ExpiresAt.Add(-time.Minute)}, nil
+}
+
+// signJWT 生成 RS256 签名的 App JWT，有效期 9 分钟（GitHub 限制不超过 10 分钟）
+func (s *appTokenSource) signJWT(now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(s.app.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(bytes.TrimSpace(data))
+	if block == nil {
+		return nil, errors.New("不是有效的 PEM 数据")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("私钥不是 RSA 类型")
+	}
+	return key, nil
+}
@@ -0,0 +1,88 @@
+package gh
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type failingSource struct{}
+
+func (failingSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("boom")
+}
+
+func newTestPool(t *testing.T, tokens ...string) (*tokenPool, *http.Client) {
+	t.Helper()
+	p, err := newTokenPool(http.DefaultTransport, tokens, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, &http.Client{Transport: p}
+}
+
+func TestPoolFailsOverOnRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer a" {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "4102444800")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	_, client := newTestPool(t, "a", "b")
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200 from the second token", resp.StatusCode)
+	}
+}
+
+func TestPoolReturnsLastResponseWhenAllRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	_, client := newTestPool(t, "a", "b")
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", resp.StatusCode)
+	}
+
+	// 凭据均已耗尽时不再发出请求，返回错误而不是 nil 响应
+	_, err = client.Get(srv.URL)
+	if !errors.Is(err, ErrNoCredential) {
+		t.Fatalf("err = %v, want ErrNoCredential", err)
+	}
+}
+
+func TestPoolTokenErrors(t *testing.T) {
+	p, client := newTestPool(t, "a")
+	p.creds[0].source = failingSource{}
+
+	_, err := client.Get("http://127.0.0.1:1/")
+	if !errors.Is(err, ErrNoCredential) || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("err = %v, want ErrNoCredential mentioning the token error", err)
+	}
+	if until := p.creds[0].state.ExhaustedUntil; time.Until(until) <= 0 {
+		t.Errorf("credential not marked unavailable: %v", until)
+	}
+}
@@ -0,0 +1,288 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Render 将 release 说明的 Markdown 渲染为安全的 HTML。
+// 输入中的原始 HTML 一律转义，输出只包含固定的一组标签；链接仅允许 http/https/mailto 协议。
+// 支持标题、段落、列表、引用、代码块、分隔线，以及行内的代码、粗体、斜体、删除线和链接。
+func Render(md string) string {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(md, "\n"))
+	return b.String()
+}
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	hrRe        = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	fenceRe     = regexp.MustCompile("^\\s{0,3}(```+|~~~+)\\s*([\\w+-]*)")
+	listItemRe  = regexp.MustCompile(`^(\s{0,3})([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	quoteRe     = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	codeSpanRe  = regexp.MustCompile("`+([^`]+)`+")
+	linkStartRe = regexp.MustCompile(`(!?)\[([^\]]*)\]\(`)
+	linkTitleRe = regexp.MustCompile(`^\s+&#34;[^"]*?&#34;`)
+	autolinkRe  = regexp.MustCompile(`(^|[\s(])(https?://[^\s<)]+)`)
+	boldRe      = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicRe    = regexp.MustCompile(`\*([^*\s][^*]*?)\*|\b_([^_]+?)_\b`)
+	strikeRe    = regexp.MustCompile(`~~(.+?)~~`)
+	placeholder = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		parts := make([]string, len(para))
+		for i, l := range para {
+			parts[i] = renderInline(strings.TrimSpace(l))
+		}
+		b.WriteString("<p>" + strings.Join(parts, "<br>\n") + "</p>\n")
+		para = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case fenceRe.MatchString(line):
+			flush()
+			m := fenceRe.FindStringSubmatch(line)
+			fence := m[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if m[2] != "" {
+				class = ` class="language-` + html.EscapeString(m[2]) + `"`
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case headingRe.MatchString(line):
+			flush()
+			m := headingRe.FindStringSubmatch(line)
+			level := len(m[1])
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(m[2]), level)
+		case hrRe.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+		case quoteRe.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && quoteRe.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteRe.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+		case listItemRe.MatchString(line):
+			flush()
+			i = renderList(b, lines, i) - 1
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+}
+
+// renderList 渲染从 start 开始的列表，返回列表之后的第一行下标
+func renderList(b *strings.Builder, lines []string, start int) int {
+	m := listItemRe.FindStringSubmatch(lines[start])
+	indent := len(m[1])
+	ordered := !strings.ContainsAny(m[2], "-*+")
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag + ">\n")
+
+	i := start
+	for i < len(lines) {
+		m := listItemRe.FindStringSubmatch(lines[i])
+		if m == nil || len(m[1]) != indent {
+			break
+		}
+		item := []string{m[3]}
+		contentIndent := len(m[1]) + len(m[2]) + 1
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// 空行之后仍缩进的内容属于当前列表项
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) > indent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(l) <= indent {
+				break
+			}
+			item = append(item, dedent(l, contentIndent))
+		}
+		var inner strings.Builder
+		renderBlocks(&inner, item)
+		content := strings.TrimSuffix(inner.String(), "\n")
+		// 紧凑列表不包裹段落
+		if strings.HasPrefix(content, "<p>") && strings.Count(content, "<p>") == 1 {
+			content = strings.Replace(strings.Replace(content, "<p>", "", 1), "</p>", "", 1)
+		}
+		b.WriteString("<li>" + content + "</li>\n")
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			break
+		}
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func leadingSpaces(s string) int {
+	n := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func dedent(s string, n int) string {
+	for n > 0 && len(s) > 0 && (s[0] == ' ' || s[0] == '\t') {
+		s = s[1:]
+		n--
+	}
+	return s
+}
+
+// renderInline 渲染行内元素。先转义整行，再把代码片段和链接替换为占位符，
+// 以免强调语法作用于代码或 URL 内部，最后还原占位符。
+func renderInline(text string) string {
+	var saved []string
+	save := func(s string) string {
+		saved = append(saved, s)
+		return fmt.Sprintf("\x00%d\x00", len(saved)-1)
+	}
+
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = codeSpanRe.ReplaceAllStringFunc(text, func(m string) string {
+		code := codeSpanRe.FindStringSubmatch(m)[1]
+		return save("<code>" + html.EscapeString(strings.TrimSpace(code)) + "</code>")
+	})
+	text = html.EscapeString(text)
+
+	text = replaceLinks(text, func(image bool, label, dest string) string {
+		label = emphasis(label)
+		if image && label == "" {
+			label = "image"
+		}
+		href, ok := safeURL(dest)
+		if !ok {
+			return save(label)
+		}
+		return save(`<a href="` + href + `" rel="nofollow noopener noreferrer" target="_blank">` + label + `</a>`)
+	})
+	text = autolinkRe.ReplaceAllStringFunc(text, func(m string) string {
+		sm := autolinkRe.FindStringSubmatch(m)
+		href, ok := safeURL(sm[2])
+		if !ok {
+			return m
+		}
+		return sm[1] + save(`<a href="`+href+`" rel="nofollow noopener noreferrer" target="_blank">`+sm[2]+`</a>`)
+	})
+
+	text = emphasis(text)
+	for placeholder.MatchString(text) {
+		text = placeholder.ReplaceAllStringFunc(text, func(m string) string {
+			var n int
+			fmt.Sscanf(strings.Trim(m, "\x00"), "%d", &n)
+			return saved[n]
+		})
+	}
+	return text
+}
+
+// replaceLinks 替换 [label](url "title") 形式的链接（text 已转义）。url 中允许成对的括号，
+// 如 https://en.wikipedia.org/wiki/Go_(programming_language)
+func replaceLinks(text string, repl func(image bool, label, dest string) string) string {
+	var b strings.Builder
+	for {
+		m := linkStartRe.FindStringSubmatchIndex(text)
+		if m == nil {
+			b.WriteString(text)
+			return b.String()
+		}
+		dest, rest, ok := parseLinkTail(text[m[1]:])
+		if !ok {
+			b.WriteString(text[:m[1]])
+			text = text[m[1]:]
+			continue
+		}
+		b.WriteString(text[:m[0]])
+		b.WriteString(repl(m[3] > m[2], text[m[4]:m[5]], dest))
+		text = rest
+	}
+}
+
+// parseLinkTail 解析链接左括号之后的部分：地址、可选的标题和右括号，返回地址和右括号之后的文本
+func parseLinkTail(s string) (dest, rest string, ok bool) {
+	s = strings.TrimLeft(s, " \t")
+	depth, end := 0, -1
+	for i := 0; i < len(s) && end < 0; i++ {
+		switch s[i] {
+		case ' ', '\t':
+			if depth > 0 {
+				return "", "", false
+			}
+			end = i
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				end = i
+			} else {
+				depth--
+			}
+		}
+	}
+	if end <= 0 {
+		return "", "", false
+	}
+	dest, s = s[:end], s[end:]
+	if loc := linkTitleRe.FindStringIndex(s); loc != nil {
+		s = s[loc[1]:]
+	}
+	s = strings.TrimLeft(s, " \t")
+	if !strings.HasPrefix(s, ")") {
+		return "", "", false
+	}
+	return dest, s[1:], true
+}
+
+func emphasis(text string) string {
+	text = boldRe.ReplaceAllString(text, "<strong>$1$2</strong>")
+	text = strikeRe.ReplaceAllString(text, "<del>$1</del>")
+	text = italicRe.ReplaceAllString(text, "<em>$1$2</em>")
+	return text
+}
+
+// safeURL 校验已转义的链接地址，只允许 http、https 和 mailto 协议
+func safeURL(escaped string) (string, bool) {
+	raw := strings.TrimSpace(html.UnescapeString(escaped))
+	lower := strings.ToLower(raw)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+		return "", false
+	}
+	return html.EscapeString(raw), true
+}
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const linkAttrs = `rel="nofollow noopener noreferrer" target="_blank"`
+
+func TestRenderInline(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"link", "[a](https://a.com)", `<a href="https://a.com" ` + linkAttrs + `>a</a>`},
+		{"link with title", `[a](https://a.com "title") and [b](https://b.com)`,
+			`<a href="https://a.com" ` + linkAttrs + `>a</a> and <a href="https://b.com" ` + linkAttrs + `>b</a>`},
+		{"parentheses in url", "[Go](https://en.wikipedia.org/wiki/Go_(programming_language)) rocks",
+			`<a href="https://en.wikipedia.org/wiki/Go_(programming_language)" ` + linkAttrs + `>Go</a> rocks`},
+		{"javascript url", "[x](javascript:alert(1))", "x"},
+		{"data url", "[x](data:text/html;base64,PHNjcmlwdD4=)", "x"},
+		{"quote in url", `[x](https://a.com/"onmouseover=alert(1))`,
+			`<a href="https://a.com/&#34;onmouseover=alert(1)" ` + linkAttrs + `>x</a>`},
+		{"image", "![](https://img/x.png)", `<a href="https://img/x.png" ` + linkAttrs + `>image</a>`},
+		{"unclosed link", "[unclosed](https://a.com",
+			`[unclosed](<a href="https://a.com" ` + linkAttrs + `>https://a.com</a>`},
+		{"autolink in parentheses", "see (https://example.com/a) ok",
+			`see (<a href="https://example.com/a" ` + linkAttrs + `>https://example.com/a</a>) ok`},
+		{"raw html", "<script>alert(1)</script>", "&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{"img onerror", `<img src=x onerror="alert(1)">`, "&lt;img src=x onerror=&#34;alert(1)&#34;&gt;"},
+		{"emphasis", "**bold** *it* _u_ ~~del~~", "<strong>bold</strong> <em>it</em> <em>u</em> <del>del</del>"},
+		{"no emphasis in code", "`**code**` <b>", "<code>**code**</code> &lt;b&gt;"},
+		{"no emphasis in url", "https://a.com/__init__", `<a href="https://a.com/__init__" ` + linkAttrs + `>https://a.com/__init__</a>`},
+		{"placeholder injection", "a\x000\x00b", "a0b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := "<p>" + tt.want + "</p>\n"
+			if got := Render(tt.in); got != want {
+				t.Errorf("Render(%q)\n got: %q\nwant: %q", tt.in, got, want)
+			}
+		})
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	in := strings.Join([]string{
+		"# Title #",
+		"",
+		"- a",
+		"- b",
+		"  - c",
+		"",
+		"1. one",
+		"2. two",
+		"",
+		"> quote",
+		"",
+		"```go",
+		"x := <1>",
+		"```",
+		"",
+		"---",
+		"line one",
+		"line two",
+	}, "\r\n")
+	want := strings.Join([]string{
+		"<h1>Title</h1>",
+		"<ul>",
+		"<li>a</li>",
+		"<li>b",
+		"<ul>",
+		"<li>c</li>",
+		"</ul></li>",
+		"</ul>",
+		"<ol>",
+		"<li>one</li>",
+		"<li>two</li>",
+		"</ol>",
+		"<blockquote>",
+		"<p>quote</p>",
+		"</blockquote>",
+		`<pre><code class="language-go">x := &lt;1&gt;</code></pre>`,
+		"<hr>",
+		"<p>line one<br>",
+		"line two</p>",
+		"",
+	}, "\n")
+	if got := Render(in); got != want {
+		t.Errorf("Render\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestRenderCodeLanguageEscaped(t *testing.T) {
+	got := Render("```\"><script>\nx\n```")
+	if strings.Contains(got, "<script>") {
+		t.Errorf("fence info string not escaped: %q", got)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"lemwood_mirror/internal/downloader"
+)
+
+// abiPatterns 按顺序匹配资源文件名中的架构关键词，x86_64 必须先于 x86 匹配
+var abiPatterns = []struct {
+	abi string
+	re  *regexp.Regexp
+}{
+	{"x86_64", boundary(`x86[_-]64|amd64|x64`)},
+	{"arm64-v8a", boundary(`arm64(-v8a)?|aarch64|armv8|v8a`)},
+	{"armeabi-v7a", boundary(`armeabi(-v7a)?|armv7l?|arm32|armhf|v7a|arm`)},
+	{"x86", boundary(`x86|i[3-6]86|ia32`)},
+}
+
+var universalPattern = boundary(`universal|all|fat|noarch`)
+
+var platformPatterns = []struct {
+	platform string
+	re       *regexp.Regexp
+}{
+	{"android", boundary(`android`)},
+	{"windows", boundary(`windows|win32|win64|win`)},
+	{"macos", boundary(`macos|mac|osx|darwin`)},
+	{"linux", boundary(`linux`)},
+}
+
+// platformByExt 根据扩展名推断资源平台，"any" 表示跨平台（如 jar）
+var platformByExt = map[string]string{
+	".apk":      "android",
+	".aab":      "android",
+	".exe":      "windows",
+	".msi":      "windows",
+	".dmg":      "macos",
+	".pkg":      "macos",
+	".appimage": "linux",
+	".deb":      "linux",
+	".rpm":      "linux",
+	".jar":      "any",
+}
+
+// abiAliases 将常见的架构写法统一为 Android ABI 名称
+var abiAliases = map[string]string{
+	"arm64": "arm64-v8a", "aarch64": "arm64-v8a", "armv8": "arm64-v8a",
+	"armeabi": "armeabi-v7a", "armv7": "armeabi-v7a", "arm": "armeabi-v7a", "arm32": "armeabi-v7a",
+	"amd64": "x86_64", "x64": "x86_64", "x86-64": "x86_64",
+	"i386": "x86", "i686": "x86", "386": "x86",
+}
+
+var platformAliases = map[string]string{
+	"win": "windows", "mac": "macos", "osx": "macos", "darwin": "macos",
+}
+
+// abiFallback 是可以运行的 32 位兼容架构
+var abiFallback = map[string]string{
+	"arm64-v8a": "armeabi-v7a",
+	"x86_64":    "x86",
+}
+
+func boundary(expr string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^a-z0-9])(?:` + expr + `)(?:[^a-z0-9]|$)`)
+}
+
+// nameABIs 返回文件名中出现的架构，已匹配的部分会被剔除以免 x86_64 再被识别为 x86
+func nameABIs(name string) []string {
+	name = strings.ToLower(name)
+	var abis []string
+	for _, p := range abiPatterns {
+		if p.re.MatchString(name) {
+			abis = append(abis, p.abi)
+			name = p.re.ReplaceAllString(name, " ")
+		}
+	}
+	return abis
+}
+
+func namePlatform(name string) string {
+	lower := strings.ToLower(name)
+	if p, ok := platformByExt[path.Ext(lower)]; ok {
+		return p
+	}
+	for _, p := range platformPatterns {
+		if p.re.MatchString(lower) {
+			return p.platform
+		}
+	}
+	return ""
+}
+
+// isAuxiliary 判断是否为校验和、签名等辅助文件
+func isAuxiliary(a downloader.ReleaseAssetSimple) bool {
+	if a.Kind == "source" || downloader.IsChecksumFile(a.Name) {
+		return true
+	}
+	// 校验和文件与下载器的校验逻辑共用同一判断，这里只补充签名等其他辅助文件
+	switch strings.ToLower(path.Ext(a.Name)) {
+	case ".sha512", ".md5", ".asc", ".sig", ".txt":
+		return true
+	}
+	return false
+}
+
+// assetScore 计算资源与请求的匹配程度，返回的 match 描述架构匹配方式，ok 为 false 表示不适用
+func assetScore(a downloader.ReleaseAssetSimple, abi, platform string) (score int, match string, ok bool) {
+	if isAuxiliary(a) {
+		return 0, "", false
+	}
+	if platform != "" {
+		switch namePlatform(a.Name) {
+		case platform:
+			score += 100
+		case "any":
+			score += 50
+		case "":
+		default:
+			return 0, "", false
+		}
+	}
+
+	// 优先使用 APK 元数据中的原生库架构，没有时根据文件名推断
+	abis := nameABIs(a.Name)
+	universal := universalPattern.MatchString(strings.ToLower(a.Name))
+	if a.Apk != nil {
+		abis = a.Apk.ABIs
+		universal = len(abis) == 0
+	}
+	if abi == "" {
+		if universal || len(abis) == 0 {
+			return score + 20, "universal", true
+		}
+		return score, "specific", true
+	}
+	switch {
+	case contains(abis, abi) && len(abis) == 1:
+		return score + 40, "exact", true
+	case contains(abis, abi):
+		return score + 30, "multi", true
+	case universal || len(abis) == 0:
+		return score + 20, "universal", true
+	case contains(abis, abiFallback[abi]):
+		return score + 10, "compatible", true
+	}
+	return 0, "", false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// handleLatestAsset 从最新版本中选出最符合 abi/platform 的资源，redirect=1 时重定向到下载地址
+func (s *State) handleLatestAsset(w http.ResponseWriter, r *http.Request, launcher string) {
+	q := r.URL.Query()
+	abi := strings.ToLower(q.Get("abi"))
+	if v, ok := abiAliases[abi]; ok {
+		abi = v
+	}
+	platform := strings.ToLower(q.Get("platform"))
+	if v, ok := platformAliases[platform]; ok {
+		platform = v
+	}
+
+	s.mu.RLock()
+	version := s.latestForChannel(r)[launcher]
+	info := s.cachedInfo(s.index[launcher][version])
+	s.mu.RUnlock()
+	if version == "" || info == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var assets []downloader.ReleaseAssetSimple
+	if b, err := json.Marshal(info["assets"]); err == nil {
+		json.Unmarshal(b, &assets)
+	}
+	best, bestScore, bestMatch := -1, -1, ""
+	for i, a := range assets {
+		if score, match, ok := assetScore(a, abi, platform); ok && score > bestScore {
+			best, bestScore, bestMatch = i, score, match
+		}
+	}
+	if best < 0 {
+		http.Error(w, "没有匹配的资源", http.StatusNotFound)
+		return
+	}
+
+	a := assets[best]
+	downloadPath := "/download/" + url.PathEscape(launcher) + "/" + url.PathEscape(version) + "/" + url.PathEscape(a.Name)
+	w.Header().Set("X-Latest-Version", version)
+	if v := q.Get("redirect"); v == "1" || v == "true" {
+		http.Redirect(w, r, downloadPath, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	result := map[string]any{
+		"launcher":      launcher,
+		"version":       version,
+		"name":          a.Name,
+		"url":           a.URL,
+		"download_path": downloadPath,
+		"size":          a.Size,
+		"match":         bestMatch,
+	}
+	if a.Apk != nil {
+		result["apk"] = a.Apk
+	}
+	json.NewEncoder(w).Encode(result)
+}
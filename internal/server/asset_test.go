@@ -0,0 +1,28 @@
+package server
+
+import (
+	"testing"
+
+	"lemwood_mirror/internal/downloader"
+)
+
+func TestIsAuxiliary(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"fcl-arm64-v8a.apk", false},
+		{"fcl.apk.sha256", true},
+		{"fcl.apk.sha256sum", true},
+		{"SHA256SUMS", true},
+		{"sha1sums", true},
+		{"checksums.txt", true},
+		{"fcl.apk.asc", true},
+		{"fcl.apk.sig", true},
+	}
+	for _, tt := range tests {
+		if got := isAuxiliary(downloader.ReleaseAssetSimple{Name: tt.name}); got != tt.want {
+			t.Errorf("isAuxiliary(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+)
+
+func TestAdminConfigRedactsSecrets(t *testing.T) {
+	cfg := &config.Config{
+		StoragePath:         "download",
+		GitHubTokens:        []string{"ghp_pool"},
+		GitHubWebhookSecret: "hook-secret",
+		Launchers: []config.LauncherConfig{
+			{Name: "a", Type: "gitlab", SourceURL: "https://gitlab.com/g/a", Token: "glpat-a"},
+			{Name: "b", SourceURL: "https://github.com/o/b"},
+		},
+	}
+	root := t.TempDir()
+	s := NewState(t.TempDir(), root, cfg)
+
+	rec := httptest.NewRecorder()
+	s.handleAdminConfig(rec, httptest.NewRequest(http.MethodGet, "/api/admin/config", nil))
+	body := rec.Body.String()
+	for _, secret := range []string{"ghp_pool", "hook-secret", "glpat-a"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("GET response leaks %q: %s", secret, body)
+		}
+	}
+	if cfg.Launchers[0].Token != "glpat-a" {
+		t.Fatal("GET must not modify the live config")
+	}
+
+	// 前端回传脱敏后的配置时，保留原有的密钥
+	var got config.Config
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	got.Launchers[1].Token = "new-token"
+	b, _ := json.Marshal(got)
+	rec = httptest.NewRecorder()
+	s.handleAdminConfig(rec, httptest.NewRequest(http.MethodPost, "/api/admin/config", bytes.NewReader(b)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST status %d: %s", rec.Code, rec.Body)
+	}
+	if s.Config.GitHubWebhookSecret != "hook-secret" || s.Config.Launchers[0].Token != "glpat-a" || s.Config.Launchers[1].Token != "new-token" {
+		t.Errorf("secrets not preserved: %+v", s.Config)
+	}
+}
+
+func TestLauncherTokensRedactedAndKept(t *testing.T) {
+	live := []config.LauncherConfig{
+		{Name: "a", Type: "gitlab", Token: "glpat-a"},
+		{Name: "b", Type: "gitea", Token: "gitea-b"},
+	}
+	redacted := redactLauncherTokens(live)
+	if redacted[0].Token != "" || redacted[1].Token != "" {
+		t.Errorf("tokens not redacted: %+v", redacted)
+	}
+	if live[0].Token != "glpat-a" {
+		t.Fatal("redaction modified the live config")
+	}
+
+	// 回传的配置中 b 换了新令牌，新增的 c 没有同名的旧启动器
+	redacted[1].Token = "new-b"
+	redacted = append(redacted, config.LauncherConfig{Name: "c"})
+	keepLauncherTokens(redacted, live)
+	if redacted[0].Token != "glpat-a" || redacted[1].Token != "new-b" || redacted[2].Token != "" {
+		t.Errorf("tokens after merge: %+v", redacted)
+	}
+}
+
+func TestAdminConfigGitHubTokens(t *testing.T) {
+	root := t.TempDir()
+	s := NewState(t.TempDir(), root, &config.Config{StoragePath: "download", GitHubTokens: []string{"ghp_a", "ghp_b"}})
+	post := func(body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleAdminConfig(rec, httptest.NewRequest(http.MethodPost, "/api/admin/config", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST %s: status %d: %s", body, rec.Code, rec.Body)
+		}
+	}
+
+	post(`{"storage_path":"download"}`)
+	if len(s.Config.GitHubTokens) != 2 {
+		t.Errorf("omitted field must keep the pool, got %v", s.Config.GitHubTokens)
+	}
+	post(`{"storage_path":"download","github_tokens":["ghp_c"]}`)
+	if len(s.Config.GitHubTokens) != 1 || s.Config.GitHubTokens[0] != "ghp_c" {
+		t.Errorf("pool not replaced: %v", s.Config.GitHubTokens)
+	}
+	post(`{"storage_path":"download","github_tokens":[]}`)
+	if len(s.Config.GitHubTokens) != 0 {
+		t.Errorf("empty list must clear the pool, got %v", s.Config.GitHubTokens)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+	"lemwood_mirror/internal/db"
+)
+
+func TestDownloadHidesInternalFiles(t *testing.T) {
+	base := t.TempDir()
+	if err := db.InitDB(base); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+	os.MkdirAll(filepath.Join(base, "fcl", "1.0"), 0o755)
+	os.WriteFile(filepath.Join(base, "fcl", "1.0", "fcl.apk"), []byte("apk"), 0o644)
+
+	mux := http.NewServeMux()
+	NewState(base, t.TempDir(), &config.Config{}).Routes(mux)
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/download/stats.db", http.StatusNotFound},
+		{"/download/stats.db-wal", http.StatusNotFound},
+		{"/download/.staging/fcl/1.0/fcl.apk", http.StatusNotFound},
+		{"/download/fcl/1.0/fcl.apk", http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
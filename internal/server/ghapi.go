@@ -0,0 +1,337 @@
+package server
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/downloader"
+)
+
+// 兼容 GitHub Releases API 的响应结构，只包含客户端检查更新常用的字段
+type ghRelease struct {
+	URL         string    `json:"url"`
+	HTMLURL     string    `json:"html_url"`
+	AssetsURL   string    `json:"assets_url"`
+	ID          int64     `json:"id"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+	TarballURL  *string   `json:"tarball_url"`
+	ZipballURL  *string   `json:"zipball_url"`
+	Assets      []ghAsset `json:"assets"`
+}
+
+type ghAsset struct {
+	URL                string    `json:"url"`
+	ID                 int64     `json:"id"`
+	Name               string    `json:"name"`
+	Label              string    `json:"label"`
+	ContentType        string    `json:"content_type"`
+	State              string    `json:"state"`
+	Size               int       `json:"size"`
+	DownloadCount      int       `json:"download_count"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	BrowserDownloadURL string    `json:"browser_download_url"`
+}
+
+// handleGitHubAPI 提供与 api.github.com 兼容的 release 查询接口：
+//
+//	/gh-api/repos/<owner>/<repo>/releases
+//	/gh-api/repos/<owner>/<repo>/releases/latest
+//	/gh-api/repos/<owner>/<repo>/releases/tags/<tag>
+//	/gh-api/repos/<owner>/<repo>/releases/<id>
+//	/gh-api/repos/<owner>/<repo>/releases/<id>/assets
+//	/gh-api/repos/<owner>/<repo>/releases/assets/<id>
+//
+// owner/repo 按启动器的上游仓库匹配，数据来自本地 index.json，下载地址指向本站的 /download/。
+// 列表接口支持 page / per_page 分页并返回 Link 头。
+func (s *State) handleGitHubAPI(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/gh-api/repos/"), "/"), "/")
+	if len(parts) < 3 || parts[2] != "releases" {
+		ghNotFound(w)
+		return
+	}
+	launchers := s.launchersForRepo(parts[0] + "/" + parts[1])
+	if len(launchers) == 0 {
+		ghNotFound(w)
+		return
+	}
+	launcher := launchers[0]
+	siteBase := s.baseURL(r)
+	apiBase := siteBase + "/gh-api/repos/" + parts[0] + "/" + parts[1]
+	rest := parts[3:]
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := s.index[launcher]
+
+	switch {
+	case len(rest) == 0:
+		writeGitHubJSON(w, paginate(w, r, siteBase, s.ghReleases(siteBase, apiBase, launcher)))
+	case len(rest) == 1 && rest[0] == "latest":
+		v := s.latest[launcher]
+		info := s.cachedInfo(versions[v])
+		if v == "" || info == nil {
+			ghNotFound(w)
+			return
+		}
+		writeGitHubJSON(w, s.ghRelease(siteBase, apiBase, launcher, v, info))
+	case len(rest) == 2 && rest[0] == "tags":
+		for v, p := range versions {
+			info := s.cachedInfo(p)
+			if info == nil {
+				continue
+			}
+			if tag, _ := info["tag_name"].(string); tag == rest[1] || v == rest[1] {
+				writeGitHubJSON(w, s.ghRelease(siteBase, apiBase, launcher, v, info))
+				return
+			}
+		}
+		ghNotFound(w)
+	case len(rest) == 2 && rest[0] == "assets":
+		for _, rel := range s.ghReleases(siteBase, apiBase, launcher) {
+			for _, a := range rel.Assets {
+				if strconv.FormatInt(a.ID, 10) != rest[1] {
+					continue
+				}
+				// 与 GitHub 一致：Accept: application/octet-stream 时重定向到文件本身
+				if strings.Contains(r.Header.Get("Accept"), "application/octet-stream") {
+					http.Redirect(w, r, a.BrowserDownloadURL, http.StatusFound)
+					return
+				}
+				writeGitHubJSON(w, a)
+				return
+			}
+		}
+		ghNotFound(w)
+	case len(rest) == 1 || len(rest) == 2 && rest[1] == "assets":
+		for _, rel := range s.ghReleases(siteBase, apiBase, launcher) {
+			if strconv.FormatInt(rel.ID, 10) != rest[0] {
+				continue
+			}
+			if len(rest) == 2 {
+				writeGitHubJSON(w, paginate(w, r, siteBase, rel.Assets))
+			} else {
+				writeGitHubJSON(w, rel)
+			}
+			return
+		}
+		ghNotFound(w)
+	default:
+		ghNotFound(w)
+	}
+}
+
+// ghReleases 返回启动器的全部 release，按发布时间从新到旧排序，调用方需持有读锁
+func (s *State) ghReleases(siteBase, apiBase, launcher string) []ghRelease {
+	list := []ghRelease{}
+	for v, p := range s.index[launcher] {
+		if info := s.cachedInfo(p); info != nil {
+			list = append(list, s.ghRelease(siteBase, apiBase, launcher, v, info))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].PublishedAt.Equal(list[j].PublishedAt) {
+			return list[i].PublishedAt.After(list[j].PublishedAt)
+		}
+		return compareVersions(list[i].TagName, list[j].TagName) > 0
+	})
+	return list
+}
+
+// ghRelease 将 index.json 转换为 GitHub 格式的 release，调用方需持有读锁
+func (s *State) ghRelease(siteBase, apiBase, launcher, version string, info map[string]interface{}) ghRelease {
+	var ri downloader.ReleaseInfo
+	if b, err := json.Marshal(info); err == nil {
+		json.Unmarshal(b, &ri)
+	}
+	if ri.TagName == "" {
+		ri.TagName = version
+	}
+	if ri.Name == "" {
+		ri.Name = ri.TagName
+	}
+	id := stableID(launcher, version)
+	rel := ghRelease{
+		URL:         apiBase + "/releases/" + strconv.FormatInt(id, 10),
+		HTMLURL:     siteBase + "/",
+		AssetsURL:   apiBase + "/releases/" + strconv.FormatInt(id, 10) + "/assets",
+		ID:          id,
+		TagName:     ri.TagName,
+		Name:        ri.Name,
+		Body:        ri.Body,
+		Prerelease:  versionChannel(version, info) == downloader.ChannelBeta,
+		CreatedAt:   ri.PublishedAt,
+		PublishedAt: ri.PublishedAt,
+		Assets:      []ghAsset{},
+	}
+	for _, a := range ri.Assets {
+		downloadURL := siteBase + "/download/" + url.PathEscape(launcher) + "/" + url.PathEscape(version) + "/" + url.PathEscape(a.Name)
+		if a.Kind == "source" {
+			// 源码压缩包对应 GitHub 的 zipball_url / tarball_url
+			u := downloadURL
+			if strings.HasSuffix(a.Name, ".zip") {
+				rel.ZipballURL = &u
+			} else {
+				rel.TarballURL = &u
+			}
+			continue
+		}
+		assetID := stableID(launcher, version, a.Name)
+		rel.Assets = append(rel.Assets, ghAsset{
+			URL:                apiBase + "/releases/assets/" + strconv.FormatInt(assetID, 10),
+			ID:                 assetID,
+			Name:               a.Name,
+			ContentType:        contentType(a.Name),
+			State:              "uploaded",
+			Size:               a.Size,
+			CreatedAt:          ri.PublishedAt,
+			UpdatedAt:          ri.PublishedAt,
+			BrowserDownloadURL: downloadURL,
+		})
+	}
+	return rel
+}
+
+// stableID 根据启动器、版本等生成稳定的数字 ID，保证同一 release 多次请求的 ID 一致
+func stableID(keys ...string) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(keys, "/")))
+	return int64(h.Sum32())
+}
+
+func contentType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".apk":
+		return "application/vnd.android.package-archive"
+	case ".jar":
+		return "application/java-archive"
+	}
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// paginate 按 GitHub 的 page / per_page 参数分页（per_page 默认 30，最大 100），
+// 并像 GitHub 一样在 Link 头中给出 first / prev / next / last 页的地址
+func paginate[T any](w http.ResponseWriter, r *http.Request, siteBase string, list []T) []T {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	if perPage > 100 {
+		perPage = 100
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	lastPage := (len(list) + perPage - 1) / perPage
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	pageURL := func(n int) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(n))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return siteBase + r.URL.Path + "?" + q.Encode()
+	}
+	var links []string
+	if page > 1 {
+		links = append(links, `<`+pageURL(min(page-1, lastPage))+`>; rel="prev"`, `<`+pageURL(1)+`>; rel="first"`)
+	}
+	if page < lastPage {
+		links = append(links, `<`+pageURL(page+1)+`>; rel="next"`, `<`+pageURL(lastPage)+`>; rel="last"`)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	start := (page - 1) * perPage
+	if start >= len(list) {
+		return []T{}
+	}
+	end := start + perPage
+	if end > len(list) {
+		end = len(list)
+	}
+	return list[start:end]
+}
+
+// baseURL 返回本站的对外访问地址。配置了 download_url_base 时直接使用；否则根据请求推断，
+// 只有来自 trusted_proxies 的请求才采用 X-Forwarded-* 头，避免客户端伪造响应中的下载地址。
+// 内部会获取读锁，调用方不能持有 s.mu。
+func (s *State) baseURL(r *http.Request) string {
+	cfg := s.CurrentConfig()
+	if base := cfg.DownloadUrlBase; base != "" {
+		if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+			base = "http://" + base
+		}
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	if trustedProxy(cfg.TrustedProxies, r.RemoteAddr) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fh := r.Header.Get("X-Forwarded-Host"); fh != "" {
+			host = strings.TrimSpace(strings.Split(fh, ",")[0])
+		}
+	}
+	return scheme + "://" + host
+}
+
+// trustedProxy 判断请求的直连地址是否在可信代理列表（IP 或 CIDR）中
+func trustedProxy(proxies []string, remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, p := range proxies {
+		if _, cidr, err := net.ParseCIDR(p); err == nil {
+			if cidr.Contains(ip) {
+				return true
+			}
+		} else if pip := net.ParseIP(p); pip != nil && pip.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeGitHubJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func ghNotFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message":           "Not Found",
+		"documentation_url": "https://docs.github.com/rest/releases/releases",
+	})
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+	"lemwood_mirror/internal/downloader"
+)
+
+// newGitHubAPIState 创建一个已镜像 fcl 1.0 的 State，fcl 的上游仓库需从页面解析
+func newGitHubAPIState(t *testing.T, cfg *config.Config) *State {
+	t.Helper()
+	cfg.Launchers = []config.LauncherConfig{
+		{Name: "fcl", SourceURL: "https://fcl.example.com/download", RepoSelector: "a.github"},
+	}
+	base := t.TempDir()
+	s := NewState(base, t.TempDir(), cfg)
+	dir := filepath.Join(base, "fcl", "1.0")
+	os.MkdirAll(dir, 0o755)
+	b, _ := json.Marshal(downloader.ReleaseInfo{
+		Launcher: "fcl",
+		TagName:  "1.0",
+		IsLatest: true,
+		Channel:  downloader.ChannelStable,
+		Assets:   []downloader.ReleaseAssetSimple{{Name: "fcl.apk", Size: 3}},
+	})
+	os.WriteFile(filepath.Join(dir, "index.json"), b, 0o644)
+	s.UpdateIndex("fcl", "1.0", filepath.Join(dir, "index.json"))
+	return s
+}
+
+func getLatest(t *testing.T, s *State, req *http.Request) (int, ghRelease) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handleGitHubAPI(rec, req)
+	var rel ghRelease
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, rel
+}
+
+func TestGitHubAPIMatchesResolvedSource(t *testing.T) {
+	s := newGitHubAPIState(t, &config.Config{})
+	url := "/gh-api/repos/FCL-Team/FoldCraftLauncher/releases/latest"
+
+	if code, _ := getLatest(t, s, httptest.NewRequest(http.MethodGet, url, nil)); code != http.StatusNotFound {
+		t.Fatalf("status %d before the source was resolved, want 404", code)
+	}
+	s.RecordSource("fcl", "https://github.com/FCL-Team/FoldCraftLauncher")
+	code, rel := getLatest(t, s, httptest.NewRequest(http.MethodGet, url, nil))
+	if code != http.StatusOK || rel.TagName != "1.0" || len(rel.Assets) != 1 {
+		t.Fatalf("status %d, release %+v", code, rel)
+	}
+}
+
+func TestGitHubAPIBaseURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.Config
+		remoteAddr string
+		want       string
+	}{
+		{"forwarded headers from client ignored", config.Config{}, "203.0.113.7:5000", "http://mirror.local"},
+		{"forwarded headers from untrusted address ignored", config.Config{TrustedProxies: []string{"10.0.0.0/8"}}, "203.0.113.7:5000", "http://mirror.local"},
+		{"trusted proxy cidr", config.Config{TrustedProxies: []string{"10.0.0.0/8"}}, "10.1.2.3:5000", "https://cdn.example.com"},
+		{"trusted proxy ip", config.Config{TrustedProxies: []string{"::1"}}, "[::1]:5000", "https://cdn.example.com"},
+		{"download_url_base wins", config.Config{DownloadUrlBase: "mirror.example.com/", TrustedProxies: []string{"10.0.0.0/8"}}, "10.1.2.3:5000", "http://mirror.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			s := newGitHubAPIState(t, &cfg)
+			s.RecordSource("fcl", "https://github.com/FCL-Team/FoldCraftLauncher")
+			req := httptest.NewRequest(http.MethodGet, "/gh-api/repos/fcl-team/foldcraftlauncher/releases/latest", nil)
+			req.Host = "mirror.local"
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-Host", "cdn.example.com, evil.example.com")
+			req.Header.Set("X-Forwarded-Proto", "https")
+
+			code, rel := getLatest(t, s, req)
+			if code != http.StatusOK || len(rel.Assets) != 1 {
+				t.Fatalf("status %d, release %+v", code, rel)
+			}
+			if got, want := rel.Assets[0].BrowserDownloadURL, tt.want+"/download/fcl/1.0/fcl.apk"; got != want {
+				t.Errorf("browser_download_url = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGitHubAPIReleaseAndAssetURLs(t *testing.T) {
+	s := newGitHubAPIState(t, &config.Config{})
+	s.RecordSource("fcl", "https://github.com/FCL-Team/FoldCraftLauncher")
+	req := httptest.NewRequest(http.MethodGet, "/gh-api/repos/FCL-Team/FoldCraftLauncher/releases/latest", nil)
+	req.Host = "mirror.local"
+	_, rel := getLatest(t, s, req)
+
+	get := func(u, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, u, nil)
+		req.Host = "mirror.local"
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		s.handleGitHubAPI(rec, req)
+		return rec
+	}
+
+	// 响应中的 url / assets_url 都应能访问
+	rec := get(rel.URL, "")
+	var byID ghRelease
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &byID) != nil || byID.TagName != "1.0" {
+		t.Fatalf("GET %s: status %d, body %s", rel.URL, rec.Code, rec.Body)
+	}
+	rec = get(rel.AssetsURL, "")
+	var assets []ghAsset
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &assets) != nil || len(assets) != 1 {
+		t.Fatalf("GET %s: status %d, body %s", rel.AssetsURL, rec.Code, rec.Body)
+	}
+	rec = get(assets[0].URL, "")
+	var asset ghAsset
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &asset) != nil || asset.Name != "fcl.apk" {
+		t.Fatalf("GET %s: status %d, body %s", assets[0].URL, rec.Code, rec.Body)
+	}
+	rec = get(assets[0].URL, "application/octet-stream")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != asset.BrowserDownloadURL {
+		t.Errorf("octet-stream asset request: status %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := get("/gh-api/repos/FCL-Team/FoldCraftLauncher/releases/12345", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown release id: status %d, want 404", rec.Code)
+	}
+}
+
+func TestGitHubAPIPagination(t *testing.T) {
+	s := newGitHubAPIState(t, &config.Config{})
+	s.RecordSource("fcl", "https://github.com/FCL-Team/FoldCraftLauncher")
+	for _, v := range []string{"1.1", "1.2"} {
+		dir := filepath.Join(s.BasePath, "fcl", v)
+		os.MkdirAll(dir, 0o755)
+		b, _ := json.Marshal(downloader.ReleaseInfo{Launcher: "fcl", TagName: v})
+		os.WriteFile(filepath.Join(dir, "index.json"), b, 0o644)
+		s.UpdateIndex("fcl", v, filepath.Join(dir, "index.json"))
+	}
+
+	list := func(query string) ([]ghRelease, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/gh-api/repos/FCL-Team/FoldCraftLauncher/releases"+query, nil)
+		req.Host = "mirror.local"
+		rec := httptest.NewRecorder()
+		s.handleGitHubAPI(rec, req)
+		var rels []ghRelease
+		if err := json.Unmarshal(rec.Body.Bytes(), &rels); err != nil {
+			t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+		}
+		return rels, rec.Header().Get("Link")
+	}
+
+	if rels, link := list(""); len(rels) != 3 || link != "" {
+		t.Errorf("default page: %d releases, Link %q", len(rels), link)
+	}
+	base := "http://mirror.local/gh-api/repos/FCL-Team/FoldCraftLauncher/releases"
+	rels, link := list("?per_page=2")
+	if len(rels) != 2 || rels[0].TagName != "1.2" {
+		t.Fatalf("first page: %+v", rels)
+	}
+	if want := `<` + base + `?page=2&per_page=2>; rel="next", <` + base + `?page=2&per_page=2>; rel="last"`; link != want {
+		t.Errorf("first page Link = %q, want %q", link, want)
+	}
+	rels, link = list("?per_page=2&page=2")
+	if len(rels) != 1 || rels[0].TagName != "1.0" {
+		t.Fatalf("second page: %+v", rels)
+	}
+	if want := `<` + base + `?page=1&per_page=2>; rel="prev", <` + base + `?page=1&per_page=2>; rel="first"`; link != want {
+		t.Errorf("second page Link = %q, want %q", link, want)
+	}
+}
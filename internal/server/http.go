@@ -6,8 +6,9 @@ import (
 	"time"
 )
 
-// StartHTTPWithScan 启动带有手动扫描端点的 HTTP 服务器
-func StartHTTPWithScan(addr string, s *State, scanFunc func()) error {
+// StartHTTPWithScan 启动带有手动扫描端点的 HTTP 服务器。
+// scanLauncherFunc 异步扫描指定名称的启动器，启动器不存在时返回 ScanNotFound。
+func StartHTTPWithScan(addr string, s *State, scanFunc func(), scanLauncherFunc func(name string) ScanStatus) error {
 	mux := http.NewServeMux()
 	s.Routes(mux)
 
@@ -17,12 +18,29 @@ func StartHTTPWithScan(addr string, s *State, scanFunc func()) error {
 			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		// 指定 launcher 参数时仅扫描该启动器
+		if name := r.URL.Query().Get("launcher"); name != "" {
+			switch scanLauncherFunc(name) {
+			case ScanNotFound:
+				http.Error(w, "Launcher not found", http.StatusNotFound)
+			case ScanQueued:
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprintf(w, "Scan already running for %s, queued to run again when it finishes\n", name)
+			default:
+				w.WriteHeader(http.StatusAccepted)
+				fmt.Fprintf(w, "Scan started for %s\n", name)
+			}
+			return
+		}
 		// 异步触发扫描
 		go scanFunc()
 		w.WriteHeader(http.StatusAccepted)
 		fmt.Fprintln(w, "Scan triggered")
 	})
 
+	// GitHub Webhook：release 事件触发对应启动器的扫描
+	mux.HandleFunc("/api/hooks/github", s.handleGitHubWebhook(scanLauncherFunc))
+
 	// 应用安全中间件
 	handler := SecurityMiddleware(mux)
 
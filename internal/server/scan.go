@@ -0,0 +1,70 @@
+package server
+
+import "sync"
+
+// ScanStatus 是触发单个启动器扫描的结果
+type ScanStatus string
+
+const (
+	ScanNotFound ScanStatus = ""        // 启动器不存在
+	ScanStarted  ScanStatus = "started" // 已开始扫描
+	ScanQueued   ScanStatus = "queued"  // 该启动器正在扫描，当前扫描结束后会再扫描一次
+)
+
+// Scanner 保证同一启动器的扫描不会并发执行。扫描进行中再次触发时不会丢弃该请求，
+// 而是记录下来，由正在进行的扫描在结束前再执行一次（期间的多次触发合并为一次），
+// 这样扫描期间发布的 release（如 Webhook 通知的版本）不必等到下一次定时扫描。
+type Scanner struct {
+	mu      sync.Mutex
+	running map[string]bool
+	pending map[string]bool
+}
+
+func NewScanner() *Scanner {
+	return &Scanner{running: make(map[string]bool), pending: make(map[string]bool)}
+}
+
+// Run 同步扫描启动器；该启动器正在扫描时只记录一次重新扫描并立即返回 ScanQueued
+func (sc *Scanner) Run(name string, scan func()) ScanStatus {
+	if !sc.start(name) {
+		return ScanQueued
+	}
+	sc.loop(name, scan)
+	return ScanStarted
+}
+
+// Trigger 与 Run 相同，但在后台执行扫描
+func (sc *Scanner) Trigger(name string, scan func()) ScanStatus {
+	if !sc.start(name) {
+		return ScanQueued
+	}
+	go sc.loop(name, scan)
+	return ScanStarted
+}
+
+// start 将启动器标记为扫描中，已在扫描时改为记录重新扫描并返回 false
+func (sc *Scanner) start(name string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.running[name] {
+		sc.pending[name] = true
+		return false
+	}
+	sc.running[name] = true
+	return true
+}
+
+// loop 执行扫描，直到扫描期间没有新的触发
+func (sc *Scanner) loop(name string, scan func()) {
+	for {
+		scan()
+		sc.mu.Lock()
+		if !sc.pending[name] {
+			delete(sc.running, name)
+			sc.mu.Unlock()
+			return
+		}
+		delete(sc.pending, name)
+		sc.mu.Unlock()
+	}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScannerQueuesRescanWhileRunning(t *testing.T) {
+	sc := NewScanner()
+	var scans atomic.Int32
+	release := make(chan struct{})
+	scan := func() {
+		if scans.Add(1) == 1 {
+			<-release // 第一次扫描进行中
+		}
+	}
+
+	if got := sc.Trigger("fcl", scan); got != ScanStarted {
+		t.Fatalf("first trigger = %q", got)
+	}
+	for scans.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 扫描期间的多次触发合并为一次重新扫描
+	for i := 0; i < 3; i++ {
+		if got := sc.Trigger("fcl", scan); got != ScanQueued {
+			t.Fatalf("trigger during scan = %q, want %q", got, ScanQueued)
+		}
+	}
+	if got := sc.Run("fcl", scan); got != ScanQueued {
+		t.Fatalf("Run during scan = %q, want %q", got, ScanQueued)
+	}
+	// 其他启动器不受影响
+	if got := sc.Run("zl", func() {}); got != ScanStarted {
+		t.Fatalf("other launcher = %q", got)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sc.mu.Lock()
+		running := sc.running["fcl"]
+		sc.mu.Unlock()
+		if !running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scan did not finish")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := scans.Load(); n != 2 {
+		t.Errorf("%d scans, want the original plus one rescan", n)
+	}
+	if got := sc.Run("fcl", scan); got != ScanStarted || scans.Load() != 3 {
+		t.Errorf("Run after scans finished = %q (%d scans)", got, scans.Load())
+	}
+}
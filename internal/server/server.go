@@ -1,6 +1,8 @@
 package server
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +21,9 @@ import (
 	"lemwood_mirror/internal/auth"
 	"lemwood_mirror/internal/config"
 	"lemwood_mirror/internal/db"
+	"lemwood_mirror/internal/downloader"
+	gh "lemwood_mirror/internal/github"
+	"lemwood_mirror/internal/markdown"
 	"lemwood_mirror/internal/stats"
 )
 
@@ -25,11 +31,17 @@ type State struct {
 	BasePath    string
 	ProjectRoot string
 	Config      *config.Config
+	GitHub      *gh.Client
+	Jobs        *downloader.Queue // 下载队列，用于管理接口查看和重试下载任务
+	// OnConfigUpdate 在管理界面保存配置后调用（如按新配置重建定时任务），为 nil 时不调用
+	OnConfigUpdate func(cfg *config.Config)
 	// 缓存状态：map[launcher]map[version]infoPath
-	mu        sync.RWMutex
-	index     map[string]map[string]string
-	latest    map[string]string
-	infoCache map[string]map[string]interface{} // 缓存 index.json 文件内容
+	mu         sync.RWMutex
+	index      map[string]map[string]string
+	latest     map[string]string
+	latestBeta map[string]string                 // beta 通道的最新版本（包含预发布）
+	infoCache  map[string]map[string]interface{} // 缓存 index.json 文件内容
+	sources    map[string]string                 // launcher -> 最近一次扫描使用的上游地址
 
 	// 登录限制
 	loginAttempts   map[string]int       // IP -> 失败次数
@@ -44,7 +56,9 @@ func NewState(base string, projectRoot string, cfg *config.Config) *State {
 		Config:      cfg,
 		index:       make(map[string]map[string]string),
 		latest:      make(map[string]string),
+		latestBeta:  make(map[string]string),
 		infoCache:   make(map[string]map[string]interface{}),
+		sources:     make(map[string]string),
 
 		loginAttempts: make(map[string]int),
 		loginLocks:    make(map[string]time.Time),
@@ -63,12 +77,13 @@ func (s *State) UpdateIndex(launcher string, version string, infoPath string) {
 	if content, err := os.ReadFile(infoPath); err == nil {
 		var info map[string]interface{}
 		if err := json.Unmarshal(content, &info); err == nil {
-			s.infoCache[infoPath] = info
+			s.infoCache[infoPath] = withBodyHTML(info)
 		}
 	}
 
 	s.latest[launcher] = s.pickLatest(s.index[launcher])
-	log.Printf("更新启动器 %s 索引: 版本=%s, 最新版本=%s", launcher, version, s.latest[launcher])
+	s.latestBeta[launcher] = s.pickLatestBeta(s.index[launcher])
+	log.Printf("更新启动器 %s 索引: 版本=%s, 最新版本=%s, beta=%s", launcher, version, s.latest[launcher], s.latestBeta[launcher])
 }
 
 // GetLatestVersion 获取启动器的最新版本号
@@ -78,6 +93,28 @@ func (s *State) GetLatestVersion(launcher string) string {
 	return s.latest[launcher]
 }
 
+// CurrentConfig 返回当前生效的配置（管理界面保存配置后会被替换）
+func (s *State) CurrentConfig() *config.Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Config
+}
+
+// RecordSource 记录启动器实际使用的上游地址（如解析后的 GitHub 仓库 URL）
+func (s *State) RecordSource(launcher string, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[launcher] = source
+}
+
+// HasVersion 判断启动器的某个版本是否已在本地索引中
+func (s *State) HasVersion(launcher string, version string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.index[launcher][version]
+	return ok
+}
+
 func (s *State) RemoveVersion(launcher string, version string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -86,6 +123,7 @@ func (s *State) RemoveVersion(launcher string, version string) {
 	}
 	delete(s.index[launcher], version)
 	s.latest[launcher] = s.pickLatest(s.index[launcher])
+	s.latestBeta[launcher] = s.pickLatestBeta(s.index[launcher])
 }
 
 // ClearLatestFlags 清除指定启动器所有版本的 is_latest 标记
@@ -311,17 +349,36 @@ func (s *State) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
 		// 返回脱敏后的配置
 		cfgCopy := *s.Config
 		cfgCopy.AdminPassword = "" // 不返回密码哈希
+		cfgCopy.GitHubTokens = nil // 不返回令牌池
+		redactWebhookSecret(&cfgCopy)
+		cfgCopy.Launchers = redactLauncherTokens(s.Config.Launchers)
 		json.NewEncoder(w).Encode(cfgCopy)
 		return
 	}
 
 	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
 		var newCfg config.Config
-		if err := json.NewDecoder(r.Body).Decode(&newCfg); err != nil {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(body, &newCfg) != nil || json.Unmarshal(body, &fields) != nil {
 			http.Error(w, "Bad Request", http.StatusBadRequest)
 			return
 		}
 
+		// 令牌池不会下发到前端，未提供该字段时保持原值；显式提供空列表（[] 或 null）表示清空
+		if _, ok := fields["github_tokens"]; !ok {
+			newCfg.GitHubTokens = s.Config.GitHubTokens
+		}
+		if newCfg.GitHubApp == nil {
+			newCfg.GitHubApp = s.Config.GitHubApp
+		}
+		keepWebhookSecret(&newCfg, s.Config)
+		keepLauncherTokens(newCfg.Launchers, s.Config.Launchers)
+
 		// 保持密码不变，除非提供了新密码
 		if newCfg.AdminPassword == "" {
 			newCfg.AdminPassword = s.Config.AdminPassword
@@ -342,6 +399,9 @@ func (s *State) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
 		s.mu.Lock()
 		s.Config = &newCfg
 		s.mu.Unlock()
+		if s.OnConfigUpdate != nil {
+			s.OnConfigUpdate(&newCfg)
+		}
 
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintln(w, "Config updated")
@@ -351,6 +411,30 @@ func (s *State) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 }
 
+// redactLauncherTokens 返回去掉访问令牌的启动器列表，复制切片以免修改正在使用的配置
+func redactLauncherTokens(launchers []config.LauncherConfig) []config.LauncherConfig {
+	list := append([]config.LauncherConfig(nil), launchers...)
+	for i := range list {
+		list[i].Token = ""
+	}
+	return list
+}
+
+// keepLauncherTokens 让未提供令牌的启动器沿用同名启动器原有的令牌（前端拿到的是脱敏后的配置）
+func keepLauncherTokens(launchers, old []config.LauncherConfig) {
+	for i, l := range launchers {
+		if l.Token != "" {
+			continue
+		}
+		for _, o := range old {
+			if o.Name == l.Name {
+				launchers[i].Token = o.Token
+				break
+			}
+		}
+	}
+}
+
 func (s *State) handleAdminBlacklist(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -536,6 +620,89 @@ func (s *State) handleAdminFileDownload(w http.ResponseWriter, r *http.Request)
 	http.ServeFile(w, r, fullPath)
 }
 
+// handleAdminGitHubTokens 返回 GitHub 凭据池中每个凭据的速率限制状态
+func (s *State) handleAdminGitHubTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	states := []gh.TokenState{}
+	if s.GitHub != nil {
+		states = s.GitHub.TokenStates()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(states)
+}
+
+// handleAdminAccelerators 返回各下载加速方式的健康度记录
+func (s *State) handleAdminAccelerators(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats, err := db.GetAcceleratorStats()
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		log.Printf("获取加速方式健康度失败: %v", err)
+		return
+	}
+	list := []*db.AcceleratorStats{}
+	for _, st := range stats {
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleAdminJobs 查看下载任务（可按 state 过滤），POST /api/admin/jobs/retry?id= 立即重试任务，不带 id 时重试全部失败的任务
+func (s *State) handleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/api/admin/jobs" && r.Method == http.MethodGet:
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 1000 {
+			limit = 200
+		}
+		jobs, err := db.ListJobs(r.URL.Query().Get("state"), limit)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			log.Printf("获取下载任务失败: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jobs)
+	case r.URL.Path == "/api/admin/jobs/retry" && r.Method == http.MethodPost:
+		var id int64
+		if v := r.URL.Query().Get("id"); v != "" {
+			var err error
+			if id, err = strconv.ParseInt(v, 10, 64); err != nil || id <= 0 {
+				http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		if s.Jobs == nil {
+			http.Error(w, "下载队列未启用", http.StatusServiceUnavailable)
+			return
+		}
+		n, err := s.Jobs.Retry(id)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			log.Printf("重试下载任务失败: %v", err)
+			return
+		}
+		if id != 0 && n == 0 {
+			http.Error(w, "任务不存在或正在下载", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int64{"retried": n})
+	case r.URL.Path == "/api/admin/jobs" || r.URL.Path == "/api/admin/jobs/retry":
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
 func (s *State) Routes(mux *http.ServeMux) {
 	// 静态 UI
 	staticDir := filepath.Join("web", "dist")
@@ -645,6 +812,16 @@ func (s *State) Routes(mux *http.ServeMux) {
 			http.NotFound(w, r)
 			return
 		}
+		if strings.HasPrefix(relPath, ".") {
+			// 禁止访问 .quarantine 等内部目录
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.Contains(relPath, "/") {
+			// 存储根目录下只有 stats.db 等内部文件（其中保存了下载任务的请求头），镜像文件位于 <launcher>/<version>/ 下
+			http.NotFound(w, r)
+			return
+		}
 
 		fullPath := filepath.Join(s.BasePath, relPath)
 		cleanPath := filepath.Clean(fullPath)
@@ -681,7 +858,14 @@ func (s *State) Routes(mux *http.ServeMux) {
 			launcher := parts[0]
 			version := parts[1]
 			fileName := filepath.Base(relPath)
-			stats.RecordDownload(r, fileName, launcher, version)
+			if fileName != downloader.ChecksumsFile {
+				stats.RecordDownload(r, fileName, launcher, version)
+			}
+		}
+		if len(parts) == 3 {
+			if digest := s.assetDigest(parts[0], parts[1], parts[2]); digest != "" {
+				w.Header().Set("Digest", digest)
+			}
 		}
 
 		http.ServeFile(w, r, cleanPath)
@@ -696,12 +880,19 @@ func (s *State) Routes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/stats", s.handleStats)
 	mux.HandleFunc("/api/auth/2fa/status", s.handle2FAStatus)
 
+	// 兼容 GitHub Releases API，供启动器的自动更新检查使用
+	mux.HandleFunc("/gh-api/repos/", s.handleGitHubAPI)
+
 	// Admin API
 	mux.Handle("/api/login", s.AdminSwitchMiddleware(http.HandlerFunc(s.handleLogin)))
 	mux.Handle("/api/admin/config", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminConfig))))
 	mux.Handle("/api/admin/blacklist", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminBlacklist))))
 	mux.Handle("/api/admin/files", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminFiles))))
 	mux.Handle("/api/admin/files/download", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminFileDownload))))
+	mux.Handle("/api/admin/github/tokens", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminGitHubTokens))))
+	mux.Handle("/api/admin/accelerators", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminAccelerators))))
+	mux.Handle("/api/admin/jobs", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminJobs))))
+	mux.Handle("/api/admin/jobs/retry", s.AdminSwitchMiddleware(http.HandlerFunc(s.AdminMiddleware(s.handleAdminJobs))))
 
 	// Admin UI
 	mux.Handle("/admin/", s.AdminSwitchMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -775,7 +966,7 @@ func SecurityMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		w.Header().Set("Access-Control-Expose-Headers", "X-Latest-Version, X-Latest-Versions")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Latest-Version, X-Latest-Versions, Digest")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -793,6 +984,10 @@ func (s *State) InitFromDisk() error {
 			return nil
 		}
 		if d.IsDir() {
+			// 跳过 .quarantine 等内部目录
+			if path != base && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		if filepath.Base(path) != "index.json" {
@@ -817,7 +1012,7 @@ func (s *State) InitFromDisk() error {
 			var info map[string]interface{}
 			if err := json.Unmarshal(content, &info); err == nil {
 				s.mu.Lock()
-				s.infoCache[path] = info
+				s.infoCache[path] = withBodyHTML(info)
 				s.mu.Unlock()
 			}
 		}
@@ -831,24 +1026,14 @@ func (s *State) pickLatest(versions map[string]string) string {
 		return ""
 	}
 
-	// 收集所有标记为 is_latest 的版本
+	// 收集所有标记为 is_latest 的版本，beta 通道的版本不参与稳定版选择
 	var latestFlagged []string
+	candidates := make(map[string]string, len(versions))
 	for v, infoPath := range versions {
-		var info map[string]interface{}
-		var exists bool
-
-		// 优先从内存缓存获取
-		info, exists = s.infoCache[infoPath]
-		if !exists {
-			// 如果内存中没有，尝试读取磁盘（通常发生在启动初始化时）
-			if content, err := os.ReadFile(infoPath); err == nil {
-				if err := json.Unmarshal(content, &info); err == nil {
-					// 这里不更新 s.infoCache，因为 pickLatest 可能在持有锁的情况下被调用
-					// 而 s.infoCache 的更新已经在 UpdateIndex 或 InitFromDisk 中处理
-				}
-			}
+		info := s.cachedInfo(infoPath)
+		if versionChannel(v, info) != "beta" {
+			candidates[v] = infoPath
 		}
-
 		if info != nil {
 			if isLatest, ok := info["is_latest"].(bool); ok && isLatest {
 				latestFlagged = append(latestFlagged, v)
@@ -872,7 +1057,7 @@ func (s *State) pickLatest(versions map[string]string) string {
 	var unstableVersions []string
 
 	for v := range versions {
-		if isStable(v) {
+		if _, ok := candidates[v]; ok {
 			stableVersions = append(stableVersions, v)
 		} else {
 			unstableVersions = append(unstableVersions, v)
@@ -904,6 +1089,76 @@ func (s *State) pickLatest(versions map[string]string) string {
 	return ""
 }
 
+// pickLatestBeta 选择 beta 通道的最新版本：所有版本（含预发布）中发布时间最新的一个
+func (s *State) pickLatestBeta(versions map[string]string) string {
+	var latest string
+	var latestTime time.Time
+	for v, infoPath := range versions {
+		var published time.Time
+		if info := s.cachedInfo(infoPath); info != nil {
+			if str, ok := info["published_at"].(string); ok {
+				published, _ = time.Parse(time.RFC3339, str)
+			}
+		}
+		if latest == "" || published.After(latestTime) || (published.Equal(latestTime) && compareVersions(v, latest) > 0) {
+			latest = v
+			latestTime = published
+		}
+	}
+	return latest
+}
+
+// assetDigest 返回资源的 RFC 3230 Digest 头（sha-256 与 sha，Base64 编码），index.json 中没有摘要时返回空字符串
+func (s *State) assetDigest(launcher, version, name string) string {
+	s.mu.RLock()
+	info := s.cachedInfo(s.index[launcher][version])
+	s.mu.RUnlock()
+	assets, _ := info["assets"].([]interface{})
+	for _, v := range assets {
+		a, _ := v.(map[string]interface{})
+		if a["name"] != name {
+			continue
+		}
+		var digests []string
+		for _, d := range []struct{ key, alg string }{{"sha256", "sha-256"}, {"sha1", "sha"}} {
+			if h, ok := a[d.key].(string); ok {
+				if b, err := hex.DecodeString(h); err == nil && len(b) > 0 {
+					digests = append(digests, d.alg+"="+base64.StdEncoding.EncodeToString(b))
+				}
+			}
+		}
+		return strings.Join(digests, ",")
+	}
+	return ""
+}
+
+// cachedInfo 获取 index.json 内容，优先使用内存缓存，缓存不存在时读取磁盘（不更新缓存，调用方可能持有锁）
+func (s *State) cachedInfo(infoPath string) map[string]interface{} {
+	if info, ok := s.infoCache[infoPath]; ok {
+		return info
+	}
+	content, err := os.ReadFile(infoPath)
+	if err != nil {
+		return nil
+	}
+	var info map[string]interface{}
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil
+	}
+	return info
+}
+
+// versionChannel 返回版本所属通道。优先使用 index.json 中记录的 channel，旧数据则根据版本号关键词推断
+func versionChannel(v string, info map[string]interface{}) string {
+	if ch, ok := info["channel"].(string); ok && ch != "" {
+		return ch
+	}
+	if isStable(v) {
+		return "stable"
+	}
+	return "beta"
+}
+
 // isStable 检查版本号是否为稳定版
 func isStable(v string) bool {
 	vLower := strings.ToLower(v)
@@ -1055,7 +1310,7 @@ func (s *State) handleLauncherStatus(w http.ResponseWriter, r *http.Request) {
                  if content, err := os.ReadFile(p); err == nil {
                      var fileInfo map[string]any
                      if err := json.Unmarshal(content, &fileInfo); err == nil {
-                         s.infoCache[p] = fileInfo // 更新缓存
+                         s.infoCache[p] = withBodyHTML(fileInfo) // 更新缓存
                          for k, val := range fileInfo {
                              // 排除 is_latest 字段
                              if k != "is_latest" {
@@ -1065,7 +1320,7 @@ func (s *State) handleLauncherStatus(w http.ResponseWriter, r *http.Request) {
                      }
                  }
              }
-             
+
              list = append(list, info)
         }
         sort.Slice(list, func(i, j int) bool {
@@ -1079,26 +1334,51 @@ func (s *State) handleLauncherStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// withBodyHTML 为早于 body_html 字段发布的版本补充渲染后的发布说明。
+// 新版本在发布时已将其写入 index.json，旧版本只在载入缓存时渲染一次，不会在每次请求时重新渲染
+func withBodyHTML(info map[string]any) map[string]any {
+	if _, ok := info["body_html"]; ok {
+		return info
+	}
+	if body, ok := info["body"].(string); ok && body != "" {
+		info["body_html"] = markdown.Render(body)
+	}
+	return info
+}
+
 func (s *State) handleFiles(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not Implemented", http.StatusNotImplemented)
 }
 
+// latestForChannel 根据 channel 查询参数返回对应的最新版本表，调用方需持有读锁
+func (s *State) latestForChannel(r *http.Request) map[string]string {
+	if r.URL.Query().Get("channel") == "beta" {
+		return s.latestBeta
+	}
+	return s.latest
+}
+
 func (s *State) handleLatestAll(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	latest := s.latestForChannel(r)
     
     // 添加 Header X-Latest-Versions
-    if b, err := json.Marshal(s.latest); err == nil {
+    if b, err := json.Marshal(latest); err == nil {
         w.Header().Set("X-Latest-Versions", string(b))
     }
-	json.NewEncoder(w).Encode(s.latest)
+	json.NewEncoder(w).Encode(latest)
 }
 
 func (s *State) handleLatestLauncher(w http.ResponseWriter, r *http.Request) {
 	launcher := strings.TrimPrefix(r.URL.Path, "/api/latest/")
+	if name, ok := strings.CutSuffix(launcher, "/asset"); ok {
+		s.handleLatestAsset(w, r, name)
+		return
+	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if val, ok := s.latest[launcher]; ok {
+	if val, ok := s.latestForChannel(r)[launcher]; ok && val != "" {
         w.Header().Set("X-Latest-Version", val)
 		w.Write([]byte(val))
 	} else {
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+)
+
+func TestLauncherStatusServesStoredBodyHTML(t *testing.T) {
+	base := t.TempDir()
+	s := NewState(base, t.TempDir(), &config.Config{})
+	for version, index := range map[string]string{
+		// 发布时已渲染，直接返回保存的结果
+		"v2": `{"tag_name":"v2","body":"**new**","body_html":"<p>stored</p>"}`,
+		// 早于 body_html 字段发布的版本，载入时渲染一次
+		"v1": `{"tag_name":"v1","body":"**old**"}`,
+	} {
+		path := filepath.Join(base, "fcl", version, "index.json")
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte(index), 0o644)
+		s.UpdateIndex("fcl", version, path)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleLauncherStatus(rec, httptest.NewRequest(http.MethodGet, "/api/status/fcl", nil))
+	var list []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body)
+	}
+	want := map[string]string{"v2": "<p>stored</p>", "v1": "<p><strong>old</strong></p>\n"}
+	for _, info := range list {
+		v, _ := info["tag_name"].(string)
+		if info["body_html"] != want[v] {
+			t.Errorf("%s: body_html = %v, want %q", v, info["body_html"], want[v])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"lemwood_mirror/internal/config"
+	gh "lemwood_mirror/internal/github"
+)
+
+// redactWebhookSecret 去掉返回给管理界面的配置中的 Webhook 密钥
+func redactWebhookSecret(cfg *config.Config) {
+	cfg.GitHubWebhookSecret = ""
+}
+
+// keepWebhookSecret 在管理界面回传的配置未提供 Webhook 密钥时沿用原有的密钥
+func keepWebhookSecret(newCfg, old *config.Config) {
+	if newCfg.GitHubWebhookSecret == "" {
+		newCfg.GitHubWebhookSecret = old.GitHubWebhookSecret
+	}
+}
+
+// handleGitHubWebhook 接收 GitHub release 事件，校验 X-Hub-Signature-256 后
+// 按 owner/repo 找到对应的启动器并触发扫描。
+func (s *State) handleGitHubWebhook(scanLauncherFunc func(name string) ScanStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.mu.RLock()
+		secret := s.Config.GitHubWebhookSecret
+		s.mu.RUnlock()
+		if secret == "" {
+			http.Error(w, "Webhook is disabled", http.StatusForbidden)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+		if err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if !validSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			log.Printf("安全警告：来自 %s 的 Webhook 签名校验失败", r.RemoteAddr)
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Header.Get("X-GitHub-Event") {
+		case "ping":
+			fmt.Fprintln(w, "pong")
+			return
+		case "release":
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var payload struct {
+			Action     string `json:"action"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if payload.Action == "deleted" || payload.Action == "unpublished" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// 启动器 -> started（已开始扫描）或 queued（正在扫描，结束后会再扫描一次）
+		triggered := map[string]ScanStatus{}
+		for _, name := range s.launchersForRepo(payload.Repository.FullName) {
+			if status := scanLauncherFunc(name); status != ScanNotFound {
+				triggered[name] = status
+			}
+		}
+		log.Printf("收到 %s 的 release 事件 (%s)，触发扫描: %v", payload.Repository.FullName, payload.Action, triggered)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]map[string]ScanStatus{"launchers": triggered})
+	}
+}
+
+// validSignature 校验 GitHub 的 sha256=<hex> 格式 HMAC 签名
+func validSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// launchersForRepo 返回上游为指定 GitHub 仓库（owner/repo，不区分大小写）的启动器。
+// 同时匹配配置中的 source_url 和扫描时解析出的仓库地址。
+func (s *State) launchersForRepo(fullName string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var names []string
+	for _, l := range s.Config.Launchers {
+		if l.Type != "" && l.Type != "github" {
+			continue
+		}
+		for _, u := range []string{s.sources[l.Name], l.SourceURL} {
+			owner, repo, err := gh.ParseOwnerRepo(u)
+			if err == nil && strings.EqualFold(owner+"/"+repo, fullName) {
+				names = append(names, l.Name)
+				break
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+)
+
+func signedRelease(t *testing.T, secret, fullName string) *http.Request {
+	t.Helper()
+	body := `{"action":"published","repository":{"full_name":"` + fullName + `"}}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "release")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestWebhookMatchesResolvedSource(t *testing.T) {
+	cfg := &config.Config{
+		GitHubWebhookSecret: "s3cret",
+		Launchers: []config.LauncherConfig{
+			// 通过页面 + repo_selector 解析仓库的启动器，source_url 不是仓库地址
+			{Name: "fcl", SourceURL: "https://fcl.example.com/download", RepoSelector: "a.github"},
+			{Name: "direct", SourceURL: "https://github.com/o/r"},
+		},
+	}
+	s := NewState(t.TempDir(), t.TempDir(), cfg)
+	var triggered []string
+	h := s.handleGitHubWebhook(func(name string) ScanStatus {
+		triggered = append(triggered, name)
+		return ScanStarted
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, signedRelease(t, "s3cret", "FCL-Team/FoldCraftLauncher"))
+	if len(triggered) != 0 {
+		t.Fatalf("triggered %v before the source was resolved", triggered)
+	}
+
+	s.RecordSource("fcl", "https://github.com/FCL-Team/FoldCraftLauncher")
+	rec = httptest.NewRecorder()
+	h(rec, signedRelease(t, "s3cret", "fcl-team/foldcraftlauncher"))
+	if rec.Code != http.StatusAccepted || len(triggered) != 1 || triggered[0] != "fcl" {
+		t.Fatalf("status %d, triggered %v", rec.Code, triggered)
+	}
+
+	rec = httptest.NewRecorder()
+	h(rec, signedRelease(t, "wrong", "o/r"))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d, want 401", rec.Code)
+	}
+}
+
+func TestWebhookUsesLiveConfig(t *testing.T) {
+	s := NewState(t.TempDir(), t.TempDir(), &config.Config{GitHubWebhookSecret: "s3cret"})
+	// 模拟管理界面保存配置后替换 s.Config
+	s.Config = &config.Config{
+		GitHubWebhookSecret: "s3cret",
+		Launchers:           []config.LauncherConfig{{Name: "new", SourceURL: "https://github.com/o/new"}},
+	}
+	rec := httptest.NewRecorder()
+	s.handleGitHubWebhook(func(string) ScanStatus { return ScanStarted })(rec, signedRelease(t, "s3cret", "o/new"))
+	var resp map[string]map[string]ScanStatus
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp["launchers"]) != 1 || resp["launchers"]["new"] != ScanStarted {
+		t.Fatalf("response %s", rec.Body)
+	}
+}
+
+func TestWebhookReportsQueuedScan(t *testing.T) {
+	s := NewState(t.TempDir(), t.TempDir(), &config.Config{
+		GitHubWebhookSecret: "s3cret",
+		Launchers:           []config.LauncherConfig{{Name: "app", SourceURL: "https://github.com/o/app"}},
+	})
+	rec := httptest.NewRecorder()
+	s.handleGitHubWebhook(func(string) ScanStatus { return ScanQueued })(rec, signedRelease(t, "s3cret", "o/app"))
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"app":"queued"`) {
+		t.Fatalf("status %d, response %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWebhookSecretRedactedAndKept(t *testing.T) {
+	live := &config.Config{GitHubWebhookSecret: "hook-secret"}
+	cfgCopy := *live
+	redactWebhookSecret(&cfgCopy)
+	if cfgCopy.GitHubWebhookSecret != "" || live.GitHubWebhookSecret != "hook-secret" {
+		t.Errorf("redacted %q, live %q", cfgCopy.GitHubWebhookSecret, live.GitHubWebhookSecret)
+	}
+	keepWebhookSecret(&cfgCopy, live)
+	if cfgCopy.GitHubWebhookSecret != "hook-secret" {
+		t.Error("secret not kept when omitted")
+	}
+	changed := config.Config{GitHubWebhookSecret: "rotated"}
+	keepWebhookSecret(&changed, live)
+	if changed.GitHubWebhookSecret != "rotated" {
+		t.Error("new secret overwritten")
+	}
+}
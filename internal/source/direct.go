@@ -0,0 +1,105 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/config"
+)
+
+// directProvider 将 SourceURL 视为单个文件的直链。
+// 版本号由响应的 Last-Modified（优先）或 ETag 生成，文件变化即视为新版本。
+type directProvider struct {
+	client *http.Client
+	url    string
+}
+
+func newDirect(lcfg config.LauncherConfig, cfg *config.Config) (*directProvider, error) {
+	if lcfg.SourceURL == "" {
+		return nil, errors.New("源 url 为空")
+	}
+	client, err := httpClient(cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &directProvider{client: client, url: lcfg.SourceURL}, nil
+}
+
+func (p *directProvider) Name() string {
+	return p.url
+}
+
+func (p *directProvider) LatestRelease(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求 %s 失败，状态码: %d", p.url, resp.StatusCode)
+	}
+
+	var version string
+	published := time.Now().UTC()
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		published = lm.UTC()
+		version = published.Format("20060102-150405")
+	} else if etag := strings.Trim(strings.TrimPrefix(resp.Header.Get("ETag"), "W/"), `"`); etag != "" {
+		if len(etag) > 12 {
+			etag = etag[:12]
+		}
+		version = etag
+	} else {
+		return nil, errors.New("上游未提供 Last-Modified 或 ETag，无法确定版本")
+	}
+
+	name := fileName(resp)
+	size := int(resp.ContentLength)
+	if size < 0 {
+		size = 0
+	}
+	return &Release{
+		TagName:     version,
+		Name:        name + " " + version,
+		PublishedAt: published,
+		Assets: []Asset{{
+			Name:        name,
+			DownloadURL: p.url,
+			Size:        size,
+		}},
+	}, nil
+}
+
+func (p *directProvider) ListReleases(ctx context.Context, limit int, prerelease bool) ([]*Release, error) {
+	// 直链没有历史版本，只能返回当前文件
+	rel, err := p.LatestRelease(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*Release{rel}, nil
+}
+
+// fileName 优先从 Content-Disposition 获取文件名，否则使用 URL 路径的最后一段
+func fileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); ValidFileName(name) {
+			return name
+		}
+	}
+	u := resp.Request.URL
+	if name, err := url.PathUnescape(path.Base(u.EscapedPath())); err == nil && ValidFileName(name) {
+		return name
+	}
+	return u.Host
+}
@@ -0,0 +1,154 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/config"
+)
+
+// giteaProvider 通过 Gitea/Forgejo 的 Releases API（/api/v1/repos/:owner/:repo/releases）获取 release，
+// 适用于 Codeberg 及自建的 Gitea/Forgejo 实例。
+type giteaProvider struct {
+	client  *http.Client
+	baseURL string // 例如 https://codeberg.org
+	repo    string // owner/repo
+	token   string
+}
+
+type giteaRelease struct {
+	ID          int64     `json:"id"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+	ZipballURL  string    `json:"zipball_url"`
+	TarballURL  string    `json:"tarball_url"`
+	Assets      []struct {
+		ID                 int64  `json:"id"`
+		Name               string `json:"name"`
+		Size               int    `json:"size"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func newGitea(lcfg config.LauncherConfig, cfg *config.Config) (*giteaProvider, error) {
+	baseURL, project, err := splitProjectURL(lcfg.SourceURL, lcfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	// Gitea 仓库路径固定为 owner/repo，忽略后续的页面路径（如 /releases）
+	parts := strings.Split(project, "/")
+	client, err := httpClient(cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &giteaProvider{client: client, baseURL: baseURL, repo: parts[0] + "/" + parts[1], token: lcfg.Token}, nil
+}
+
+func (p *giteaProvider) Name() string {
+	return p.baseURL + "/" + p.repo
+}
+
+func (p *giteaProvider) LatestRelease(ctx context.Context) (*Release, error) {
+	releases, err := p.ListReleases(ctx, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, errors.New("仓库没有任何正式 release")
+	}
+	return releases[0], nil
+}
+
+func (p *giteaProvider) ListReleases(ctx context.Context, limit int, prerelease bool) ([]*Release, error) {
+	var result []*Release
+	// Gitea 默认单页最多 50 条
+	perPage := 50
+	for page := 1; ; page++ {
+		apiURL := fmt.Sprintf("%s/api/v1/repos/%s/releases?limit=%d&page=%d", p.baseURL, p.repo, perPage, page)
+		var releases []giteaRelease
+		if err := p.get(ctx, apiURL, &releases); err != nil {
+			return nil, err
+		}
+		for _, rel := range releases {
+			if rel.Draft || (rel.Prerelease && !prerelease) {
+				continue
+			}
+			result = append(result, p.convert(rel))
+			if limit > 0 && len(result) >= limit {
+				return result, nil
+			}
+		}
+		// 实例可能限制了单页最大条数，以返回空页作为结束条件
+		if len(releases) == 0 {
+			return result, nil
+		}
+	}
+}
+
+func (p *giteaProvider) get(ctx context.Context, apiURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "token "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 失败，状态码: %d", apiURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("解析 Gitea 响应失败: %w", err)
+	}
+	return nil
+}
+
+func (p *giteaProvider) convert(rel giteaRelease) *Release {
+	published := rel.PublishedAt
+	if published.IsZero() {
+		published = rel.CreatedAt
+	}
+	r := &Release{
+		ID:          rel.ID,
+		TagName:     rel.TagName,
+		Name:        rel.Name,
+		Body:        rel.Body,
+		PublishedAt: published,
+		Prerelease:  rel.Prerelease,
+		Draft:       rel.Draft,
+	}
+	for _, a := range rel.Assets {
+		r.Assets = append(r.Assets, Asset{
+			Name:        a.Name,
+			DownloadURL: a.BrowserDownloadURL,
+			Size:        a.Size,
+		})
+	}
+	repoName := path.Base(p.repo)
+	for _, arc := range []struct{ ext, url string }{{"zip", rel.ZipballURL}, {"tar.gz", rel.TarballURL}} {
+		if arc.url == "" {
+			continue
+		}
+		r.SourceArchives = append(r.SourceArchives, Asset{
+			Name:        archiveName(repoName, rel.TagName, arc.ext),
+			DownloadURL: arc.url,
+			Kind:        KindSource,
+		})
+	}
+	return r
+}
@@ -0,0 +1,85 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v50/github"
+	"lemwood_mirror/internal/browser"
+	"lemwood_mirror/internal/config"
+	gh "lemwood_mirror/internal/github"
+)
+
+// gitHubProvider 通过 GitHub Releases API 获取 release
+type gitHubProvider struct {
+	client  *gh.Client
+	repoURL string
+	owner   string
+	repo    string
+}
+
+func newGitHub(lcfg config.LauncherConfig, ghc *gh.Client) (*gitHubProvider, error) {
+	repoURL, err := browser.ResolveRepoURL(lcfg.SourceURL, lcfg.RepoSelector)
+	if err != nil {
+		return nil, fmt.Errorf("解析仓库地址失败: %w", err)
+	}
+	owner, repo, err := gh.ParseOwnerRepo(repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析 owner/repo 失败: %w", err)
+	}
+	return &gitHubProvider{client: ghc, repoURL: repoURL, owner: owner, repo: repo}, nil
+}
+
+func (p *gitHubProvider) Name() string {
+	return p.repoURL
+}
+
+func (p *gitHubProvider) LatestRelease(ctx context.Context) (*Release, error) {
+	rel, _, err := p.client.LatestRelease(ctx, p.owner, p.repo)
+	if err != nil {
+		return nil, err
+	}
+	return p.fromGitHub(rel), nil
+}
+
+func (p *gitHubProvider) ListReleases(ctx context.Context, limit int, prerelease bool) ([]*Release, error) {
+	releases, _, err := p.client.ListReleases(ctx, p.owner, p.repo, limit, prerelease)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Release, 0, len(releases))
+	for _, rel := range releases {
+		result = append(result, p.fromGitHub(rel))
+	}
+	return result, nil
+}
+
+func (p *gitHubProvider) fromGitHub(rel *github.RepositoryRelease) *Release {
+	r := &Release{
+		ID:          rel.GetID(),
+		TagName:     rel.GetTagName(),
+		Name:        rel.GetName(),
+		Body:        rel.GetBody(),
+		PublishedAt: rel.GetPublishedAt().Time,
+		Prerelease:  rel.GetPrerelease(),
+		Draft:       rel.GetDraft(),
+	}
+	for _, a := range rel.Assets {
+		r.Assets = append(r.Assets, Asset{
+			Name:        a.GetName(),
+			DownloadURL: a.GetBrowserDownloadURL(),
+			Size:        a.GetSize(),
+		})
+	}
+	// 使用 github.com 上的归档地址而不是 API 的 zipball_url，避免消耗 API 配额，也便于走下载加速
+	if tag := rel.GetTagName(); tag != "" {
+		for _, ext := range []string{"zip", "tar.gz"} {
+			r.SourceArchives = append(r.SourceArchives, Asset{
+				Name:        archiveName(p.repo, tag, ext),
+				DownloadURL: fmt.Sprintf("https://github.com/%s/%s/archive/refs/tags/%s.%s", p.owner, p.repo, tag, ext),
+				Kind:        KindSource,
+			})
+		}
+	}
+	return r
+}
@@ -0,0 +1,189 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"lemwood_mirror/internal/config"
+)
+
+// gitLabProvider 通过 GitLab Releases API（/api/v4/projects/:id/releases）获取 release，
+// 支持 gitlab.com 及自建实例。release 的资源来自 assets.links，包括指向 generic package 的链接。
+type gitLabProvider struct {
+	client  *http.Client
+	baseURL string // 例如 https://gitlab.com
+	project string // 项目路径，例如 group/subgroup/project
+	token   string
+}
+
+type gitLabRelease struct {
+	TagName         string    `json:"tag_name"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Assets          struct {
+		Links []struct {
+			ID             int64  `json:"id"`
+			Name           string `json:"name"`
+			URL            string `json:"url"`
+			DirectAssetURL string `json:"direct_asset_url"`
+			// DirectAssetPath 是资源的文件路径（GitLab 15.9 之前为 filepath）
+			DirectAssetPath string `json:"direct_asset_path"`
+			Filepath        string `json:"filepath"`
+			LinkType        string `json:"link_type"`
+		} `json:"links"`
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+	} `json:"assets"`
+}
+
+func newGitLab(lcfg config.LauncherConfig, cfg *config.Config) (*gitLabProvider, error) {
+	baseURL, project, err := splitProjectURL(lcfg.SourceURL, lcfg.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClient(cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return &gitLabProvider{client: client, baseURL: baseURL, project: project, token: lcfg.Token}, nil
+}
+
+func (p *gitLabProvider) Name() string {
+	return p.baseURL + "/" + p.project
+}
+
+func (p *gitLabProvider) LatestRelease(ctx context.Context) (*Release, error) {
+	releases, err := p.ListReleases(ctx, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, errors.New("项目没有任何 release")
+	}
+	return releases[0], nil
+}
+
+func (p *gitLabProvider) ListReleases(ctx context.Context, limit int, prerelease bool) ([]*Release, error) {
+	var result []*Release
+	perPage := 100
+	if limit > 0 && limit < perPage {
+		perPage = limit
+	}
+	page := "1"
+	for page != "" {
+		apiURL := fmt.Sprintf("%s/api/v4/projects/%s/releases?per_page=%d&page=%s", p.baseURL, url.PathEscape(p.project), perPage, page)
+		var releases []gitLabRelease
+		header, err := p.get(ctx, apiURL, &releases)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range releases {
+			// 尚未到发布时间的 release 视为草稿，不进行镜像
+			if rel.UpcomingRelease {
+				continue
+			}
+			result = append(result, p.convert(rel))
+			if limit > 0 && len(result) >= limit {
+				return result, nil
+			}
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return result, nil
+}
+
+func (p *gitLabProvider) get(ctx context.Context, apiURL string, v any) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求 %s 失败，状态码: %d", apiURL, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("解析 GitLab 响应失败: %w", err)
+	}
+	return resp.Header, nil
+}
+
+func (p *gitLabProvider) convert(rel gitLabRelease) *Release {
+	published := rel.ReleasedAt
+	if published.IsZero() {
+		published = rel.CreatedAt
+	}
+	r := &Release{
+		TagName:     rel.TagName,
+		Name:        rel.Name,
+		Body:        rel.Description,
+		PublishedAt: published,
+	}
+	seen := make(map[string]bool)
+	for _, link := range rel.Assets.Links {
+		downloadURL := link.DirectAssetURL
+		if downloadURL == "" {
+			downloadURL = link.URL
+		}
+		assetPath := link.DirectAssetPath
+		if assetPath == "" {
+			assetPath = link.Filepath
+		}
+		name := linkFileName(assetPath, downloadURL)
+		if !ValidFileName(name) || seen[name] {
+			log.Printf("GitLab 资源 %q 的文件名 %q 无效或重复，跳过", link.Name, name)
+			continue
+		}
+		seen[name] = true
+		r.Assets = append(r.Assets, Asset{
+			Name:        name,
+			DownloadURL: downloadURL,
+		})
+	}
+	for _, src := range rel.Assets.Sources {
+		if src.Format != "zip" && src.Format != "tar.gz" {
+			continue
+		}
+		r.SourceArchives = append(r.SourceArchives, Asset{
+			Name:        archiveName(path.Base(p.project), rel.TagName, src.Format),
+			DownloadURL: src.URL,
+			Kind:        KindSource,
+		})
+	}
+	return r
+}
+
+// linkFileName 返回链接资源的本地文件名。link 的 name 是自由填写的标签（可能包含空格、斜杠或没有扩展名），
+// 因此优先使用 direct_asset_path 的最后一段，其次是下载地址路径的最后一段
+func linkFileName(assetPath, downloadURL string) string {
+	if assetPath != "" {
+		return path.Base(assetPath)
+	}
+	u, err := url.Parse(downloadURL)
+	if err != nil {
+		return ""
+	}
+	name, err := url.PathUnescape(path.Base(u.EscapedPath()))
+	if err != nil {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,192 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"lemwood_mirror/internal/config"
+)
+
+// fakeGitLab 模拟 GitLab Releases API，每页返回一个 release
+func fakeGitLab(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fsub%2Fapp/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		if token != "" && r.Header.Get("PRIVATE-TOKEN") != token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprintf(w, `[{"tag_name":"v2.0","name":"未来版本","upcoming_release":true}]`)
+		case "2":
+			w.Header().Set("X-Next-Page", "3")
+			fmt.Fprintf(w, `[{
+				"tag_name": "v1.1",
+				"name": "Release 1.1",
+				"description": "changelog",
+				"created_at": "2026-01-01T00:00:00Z",
+				"released_at": "2026-01-02T00:00:00Z",
+				"assets": {
+					"links": [
+						{"name": "Windows installer", "url": "%[1]s/files/app-setup.exe"},
+						{"name": "macOS", "url": "%[1]s/x", "direct_asset_url": "%[1]s/group/sub/app/-/releases/v1.1/downloads/binaries/app.dmg", "direct_asset_path": "/binaries/app.dmg"},
+						{"name": "legacy", "url": "https://example.com/dl?id=1", "filepath": "/bin/app.jar"},
+						{"name": "../../etc/passwd", "url": "%[1]s/files/.."},
+						{"name": "dup", "url": "%[1]s/other/app-setup.exe"},
+						{"name": "encoded", "url": "%[1]s/files/a%%2F..%%2Fb.apk"}
+					],
+					"sources": [
+						{"format": "zip", "url": "%[1]s/group/sub/app/-/archive/v1.1/app-v1.1.zip"},
+						{"format": "tar.bz2", "url": "%[1]s/group/sub/app/-/archive/v1.1/app-v1.1.tar.bz2"}
+					]
+				}
+			}]`, srv.URL)
+		default:
+			fmt.Fprint(w, `[{"tag_name":"v1.0","created_at":"2025-12-01T00:00:00Z","assets":{"links":[]}}]`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestGitLab(t *testing.T, srv *httptest.Server, token string) *gitLabProvider {
+	t.Helper()
+	p, err := newGitLab(config.LauncherConfig{SourceURL: srv.URL + "/group/sub/app", Token: token}, &config.Config{})
+	if err != nil {
+		t.Fatalf("newGitLab: %v", err)
+	}
+	return p
+}
+
+func TestGitLabListReleases(t *testing.T) {
+	srv := fakeGitLab(t, "secret")
+	p := newTestGitLab(t, srv, "secret")
+
+	releases, err := p.ListReleases(context.Background(), 0, false)
+	if err != nil {
+		t.Fatalf("ListReleases: %v", err)
+	}
+	if len(releases) != 2 {
+		t.Fatalf("got %d releases, want 2 (upcoming release skipped)", len(releases))
+	}
+	rel := releases[0]
+	if rel.Version() != "v1.1" || rel.Body != "changelog" || rel.PublishedAt.Day() != 2 {
+		t.Errorf("unexpected release %+v", rel)
+	}
+
+	want := map[string]string{
+		"app-setup.exe": srv.URL + "/files/app-setup.exe",
+		"app.dmg":       srv.URL + "/group/sub/app/-/releases/v1.1/downloads/binaries/app.dmg",
+		"app.jar":       "https://example.com/dl?id=1",
+	}
+	if len(rel.Assets) != len(want) {
+		t.Fatalf("got assets %+v, want %v", rel.Assets, want)
+	}
+	lcfg := config.LauncherConfig{Type: TypeGitLab, SourceURL: srv.URL + "/group/sub/app", Token: "secret"}
+	for _, a := range rel.Assets {
+		if want[a.Name] != a.DownloadURL {
+			t.Errorf("asset %q: url %q, want %q", a.Name, a.DownloadURL, want[a.Name])
+		}
+		if a.Header != nil {
+			t.Errorf("asset %q: provider attached headers %v", a.Name, a.Header)
+		}
+		// 令牌只发送给本实例
+		sameInstance := a.Name != "app.jar"
+		if got := AssetHeader(lcfg, a.DownloadURL).Get("PRIVATE-TOKEN") == "secret"; got != sameInstance {
+			t.Errorf("asset %q: token attached = %v, want %v", a.Name, got, sameInstance)
+		}
+	}
+
+	if len(rel.SourceArchives) != 1 || rel.SourceArchives[0].Name != "app-v1.1.zip" || rel.SourceArchives[0].Kind != KindSource {
+		t.Errorf("unexpected source archives %+v", rel.SourceArchives)
+	}
+}
+
+func TestGitLabLatestRelease(t *testing.T) {
+	srv := fakeGitLab(t, "")
+	rel, err := newTestGitLab(t, srv, "").LatestRelease(context.Background())
+	if err != nil {
+		t.Fatalf("LatestRelease: %v", err)
+	}
+	if rel.Version() != "v1.1" {
+		t.Errorf("latest = %s, want v1.1", rel.Version())
+	}
+}
+
+func TestGitLabUnauthorized(t *testing.T) {
+	srv := fakeGitLab(t, "secret")
+	if _, err := newTestGitLab(t, srv, "wrong").LatestRelease(context.Background()); err == nil {
+		t.Fatal("expected error for rejected token")
+	}
+}
+
+func TestLinkFileName(t *testing.T) {
+	tests := []struct {
+		assetPath, url, want string
+		valid                bool
+	}{
+		{"", "https://gitlab.com/files/app.apk", "app.apk", true},
+		{"/binaries/app.dmg", "https://gitlab.com/x", "app.dmg", true},
+		{"", "https://gitlab.com/files/My%20App.exe", "My App.exe", true},
+		{"", "https://gitlab.com/files/..", "..", false},
+		{"", "https://gitlab.com/", "/", false},
+		{"", "https://gitlab.com/files/a%2F..%2Fb", "a/../b", false},
+		{"", "https://gitlab.com/files/a%5Cb", `a\b`, false},
+		{"/", "https://gitlab.com/files/app.apk", "/", false},
+	}
+	for _, tt := range tests {
+		got := linkFileName(tt.assetPath, tt.url)
+		if got != tt.want || ValidFileName(got) != tt.valid {
+			t.Errorf("linkFileName(%q, %q) = %q (valid %v), want %q (valid %v)", tt.assetPath, tt.url, got, ValidFileName(got), tt.want, tt.valid)
+		}
+	}
+}
+
+func TestCheckRedirectDropsTokenOnHostChange(t *testing.T) {
+	var leaked atomic.Bool
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "" || r.Header.Get("Authorization") != "" {
+			leaked.Store(true)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer storage.Close()
+	instance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, storage.URL+"/object", http.StatusFound)
+	}))
+	defer instance.Close()
+
+	client, err := httpClient("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, instance.URL+"/api/v4/projects/1/packages/generic/app/1.0/app.apk", nil)
+	req.Header.Set("PRIVATE-TOKEN", "secret")
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if leaked.Load() {
+		t.Error("token was forwarded to another host")
+	}
+}
@@ -0,0 +1,190 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"lemwood_mirror/internal/config"
+	gh "lemwood_mirror/internal/github"
+)
+
+// 上游类型，对应 LauncherConfig.Type
+const (
+	TypeGitHub  = "github"
+	TypeGitLab  = "gitlab"
+	TypeGitea   = "gitea"
+	TypeForgejo = "forgejo"
+	TypeDirect  = "direct"
+)
+
+// KindSource 标记源码压缩包资源
+const KindSource = "source"
+
+// Release 是与上游平台无关的 release 模型
+type Release struct {
+	ID          int64
+	TagName     string
+	Name        string
+	Body        string // Markdown 格式的发布说明
+	PublishedAt time.Time
+	Prerelease  bool
+	Draft       bool
+	Assets      []Asset
+	// SourceArchives 是该 release 对应的源码压缩包（zip / tar.gz），Kind 为 KindSource
+	SourceArchives []Asset
+}
+
+// Asset 是 release 中的单个可下载文件
+type Asset struct {
+	Name        string
+	DownloadURL string
+	Size        int
+	Kind        string // 资源类型，源码压缩包为 KindSource，普通资源为空
+	// Header 是下载该文件时需要附带的请求头（如私有实例的访问令牌），由下载器在执行任务时通过 AssetHeader 生成，
+	// 不会写入 index.json 或下载任务
+	Header http.Header
+}
+
+// Version 返回 release 对应的版本目录名：优先使用 tag，其次是名称，最后是 ID。
+func (r *Release) Version() string {
+	if r.TagName != "" {
+		return r.TagName
+	}
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("%d", r.ID)
+}
+
+// Provider 从某个上游平台获取 release 信息
+type Provider interface {
+	// Name 返回上游的描述（如仓库地址），用于日志
+	Name() string
+	// LatestRelease 获取最新的正式 release
+	LatestRelease(ctx context.Context) (*Release, error)
+	// ListReleases 获取最近的 limit 个 release（不含草稿），按发布时间从新到旧排列。
+	// prerelease 为 false 时跳过预发布版本。
+	ListReleases(ctx context.Context, limit int, prerelease bool) ([]*Release, error)
+}
+
+// New 根据启动器配置创建对应的上游 Provider。Type 为空时默认为 GitHub。
+func New(lcfg config.LauncherConfig, cfg *config.Config, ghc *gh.Client) (Provider, error) {
+	switch lcfg.Type {
+	case "", TypeGitHub:
+		return newGitHub(lcfg, ghc)
+	case TypeGitLab:
+		return newGitLab(lcfg, cfg)
+	case TypeGitea, TypeForgejo:
+		return newGitea(lcfg, cfg)
+	case TypeDirect:
+		return newDirect(lcfg, cfg)
+	default:
+		return nil, fmt.Errorf("未知的上游类型 %q", lcfg.Type)
+	}
+}
+
+// LatestPrerelease 获取最新的预发布版本。如果最新发布的 release 是正式版，则返回 nil。
+func LatestPrerelease(ctx context.Context, p Provider) (*Release, error) {
+	releases, err := p.ListReleases(ctx, 1, true)
+	if err != nil || len(releases) == 0 || !releases[0].Prerelease {
+		return nil, err
+	}
+	return releases[0], nil
+}
+
+// httpClient 创建访问上游 API 的 HTTP 客户端，proxyURL 非空时走代理
+func httpClient(proxyURL string) (*http.Client, error) {
+	client := &http.Client{Timeout: 30 * time.Second, CheckRedirect: CheckRedirect}
+	if proxyURL != "" {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("解析代理URL失败: %w", err)
+		}
+		client.Transport = &http.Transport{Proxy: http.ProxyURL(proxy)}
+	}
+	return client, nil
+}
+
+// archiveName 生成源码压缩包的文件名，如 repo-v1.0.zip；tag 中的路径分隔符会被替换
+func archiveName(repo, tag, ext string) string {
+	tag = strings.NewReplacer("/", "-", "\\", "-").Replace(tag)
+	return repo + "-" + tag + "." + ext
+}
+
+// splitProjectURL 将项目 URL 拆分为实例地址和项目路径。
+// baseURL 为空时取 projectURL 的协议与主机；自建实例部署在子路径下时需要显式配置 baseURL。
+func splitProjectURL(projectURL, baseURL string) (string, string, error) {
+	u, err := url.Parse(projectURL)
+	if err != nil || u.Host == "" {
+		return "", "", fmt.Errorf("无效的项目 url: %s", projectURL)
+	}
+	if baseURL == "" {
+		baseURL = u.Scheme + "://" + u.Host
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	if !strings.HasPrefix(projectURL, baseURL+"/") {
+		return "", "", fmt.Errorf("项目 url %s 不在实例 %s 下", projectURL, baseURL)
+	}
+	project := strings.Trim(strings.TrimPrefix(projectURL, baseURL), "/")
+	project = strings.TrimSuffix(project, ".git")
+	if i := strings.Index(project, "/-/"); i >= 0 {
+		project = project[:i]
+	}
+	if project == "" || !strings.Contains(project, "/") {
+		return "", "", fmt.Errorf("无效的项目 url，需要 %s/<namespace>/<project>", baseURL)
+	}
+	return baseURL, project, nil
+}
+
+// ValidFileName 判断资源名或版本名能否直接用作本地文件名或目录名：不能为空或 . / ..，不能包含路径分隔符
+func ValidFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// AssetHeader 返回下载启动器资源时需要附带的请求头。私有 GitLab/Gitea 实例的令牌仅附带给指向本实例的链接，
+// 避免泄露给第三方站点；令牌不随下载任务保存，每次下载时按当前配置重新生成
+func AssetHeader(lcfg config.LauncherConfig, downloadURL string) http.Header {
+	if lcfg.Token == "" {
+		return nil
+	}
+	var key, value string
+	switch lcfg.Type {
+	case TypeGitLab:
+		key, value = "PRIVATE-TOKEN", lcfg.Token
+	case TypeGitea, TypeForgejo:
+		key, value = "Authorization", "token "+lcfg.Token
+	default:
+		return nil
+	}
+	baseURL, _, err := splitProjectURL(lcfg.SourceURL, lcfg.BaseURL)
+	if err != nil || !sameHost(baseURL, downloadURL) {
+		return nil
+	}
+	h := http.Header{}
+	h.Set(key, value)
+	return h
+}
+
+// CheckRedirect 在重定向到其他主机时删除访问令牌。GitLab 的 generic package 和附件等链接会重定向到对象存储，
+// 而 http.Client 只会自动删除 Authorization，不会删除 PRIVATE-TOKEN 等自定义请求头
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("重定向次数过多")
+	}
+	if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		req.Header.Del("PRIVATE-TOKEN")
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+func sameHost(a, b string) bool {
+	ua, err1 := url.Parse(a)
+	ub, err2 := url.Parse(b)
+	return err1 == nil && err2 == nil && strings.EqualFold(ua.Host, ub.Host)
+}
@@ -8,6 +8,7 @@ document.addEventListener('DOMContentLoaded', () => {
     const tabContents = document.querySelectorAll('.tab-content');
 
     let currentPath = '';
+    let loadedConfig = {}; // 最近一次加载的配置，保存时保留表单未展示的字段
     let adminUser = 'admin';
 
     // 检查是否有现有 token
@@ -148,6 +149,7 @@ document.addEventListener('DOMContentLoaded', () => {
                 throw new Error(`HTTP error! status: ${res.status}`);
             }
             const config = await res.json();
+            loadedConfig = config;
             console.log('Config loaded:', config);
             
             const form = document.getElementById('config-form');
@@ -214,6 +216,8 @@ document.addEventListener('DOMContentLoaded', () => {
             </div>
         `;
         
+        // 保留表单未展示的字段（如 keep_history），保存时原样回写
+        item.dataset.extra = JSON.stringify(data);
         item.querySelector('.remove-btn').onclick = () => item.remove();
         container.appendChild(item);
     }
@@ -264,6 +268,7 @@ document.addEventListener('DOMContentLoaded', () => {
         const items = document.querySelectorAll('.launcher-item');
         items.forEach(item => {
             launchers.push({
+                ...JSON.parse(item.dataset.extra || '{}'),
                 name: item.querySelector('[name="l_name"]').value,
                 source_url: item.querySelector('[name="l_url"]').value,
                 repo_selector: item.querySelector('[name="l_selector"]').value
@@ -271,6 +276,7 @@ document.addEventListener('DOMContentLoaded', () => {
         });
 
         const config = {
+            ...loadedConfig,
             server_port: parseInt(form.server_port.value),
             check_cron: form.check_cron.value,
             storage_path: form.storage_path.value,